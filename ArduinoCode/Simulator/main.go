@@ -0,0 +1,281 @@
+// Simulator pretends to be the voltmeter board. It opens a pseudo-terminal
+// and writes the same framed byte stream as the firmware, driven by a
+// synthetic waveform, optionally corrupting it so the reader's recovery can
+// be exercised without hardware.
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"voltmeter/protocol"
+)
+
+// Firmware version announced in info frames, matching voltmeter.go
+var firmwareVersion = []uint16{1, 2, 0}
+
+// Info frames are repeated as often as the firmware does
+const infoInterval = 60
+
+// Analog pins on the simulated board and the firmware's averaging limit
+const (
+	pins         = protocol.BoardChannels
+	maxAveraging = protocol.MaxAveraging
+)
+
+// faults describes how the byte stream is damaged.
+type faults struct {
+	Drop     float64       // probability of losing each byte
+	Garbage  float64       // probability of noise bytes before a frame
+	Every    time.Duration // time between disconnects, zero for never
+	Downtime time.Duration // how long the device stays unplugged
+}
+
+// device holds the firmware state that survives a disconnect.
+type device struct {
+	wave      waveform
+	config    protocol.Config
+	reference float64
+	bits      int
+	faults    faults
+	rng       *rand.Rand
+	boot      time.Time
+	seq       uint16
+	samples   int
+	frame     []byte
+}
+
+func main() {
+	kind := flag.String("wave", "sine", "waveform: constant, sine, noise or step")
+	offset := flag.Float64("offset", 1.65, "signal offset in volts")
+	amplitude := flag.Float64("amplitude", 1.0, "signal amplitude in volts")
+	period := flag.Duration("period", 10*time.Second, "period of the sine and step waveforms")
+	channels := flag.Int("channels", 2, "number of ADC channels active at boot")
+	reference := flag.Float64("vref", 3.3, "ADC reference voltage in volts")
+	bits := flag.Int("bits", 16, "ADC resolution in bits (TinyGo scales readings to 16)")
+	interval := flag.Duration("interval", time.Second, "time between samples at boot")
+	seed := flag.Int64("seed", 38, "random seed for noise and faults")
+	link := flag.String("link", "", "symlink kept pointing at the current pty, e.g. /tmp/ttyVOLT")
+	drop := flag.Float64("drop", 0, "probability of dropping each byte")
+	garbage := flag.Float64("garbage", 0, "probability of writing noise bytes before each frame")
+	every := flag.Duration("disconnect-every", 0, "unplug the device this often (0 disables)")
+	downtime := flag.Duration("downtime", 3*time.Second, "how long the device stays unplugged")
+	flag.Parse()
+
+	if *channels < 1 || *channels > pins {
+		log.Fatalf("channels must be 1 to %d", pins)
+	}
+	if *interval < time.Millisecond || *interval > 65535*time.Millisecond {
+		log.Fatal("interval must be 1ms to 65.535s")
+	}
+	if *bits < 1 || *bits > 16 {
+		log.Fatal("bits must be 1 to 16")
+	}
+	rng := rand.New(rand.NewSource(*seed))
+	wave, err := newWaveform(*kind, *offset, *amplitude, period.Seconds(), rng)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	d := &device{
+		wave: wave,
+		config: clamp(protocol.Config{
+			Channels:  uint16(1)<<uint(*channels) - 1,
+			Interval:  uint16(*interval / time.Millisecond),
+			Averaging: 1,
+		}),
+		reference: *reference,
+		bits:      *bits,
+		faults:    faults{Drop: *drop, Garbage: *garbage, Every: *every, Downtime: *downtime},
+		rng:       rng,
+		boot:      time.Now(),
+		frame:     make([]byte, 0, protocol.FrameSize(3+pins)),
+	}
+
+	for {
+		p, err := openPTY()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *link != "" {
+			os.Remove(*link)
+			if err := os.Symlink(p.name, *link); err != nil {
+				log.Fatal(err)
+			}
+		}
+		log.Printf("simulating voltmeter on %s", p.name)
+
+		err = d.run(p.master)
+		p.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("unplugged for %v", d.faults.Downtime)
+		if *link != "" {
+			os.Remove(*link)
+		}
+		time.Sleep(d.faults.Downtime)
+	}
+}
+
+// run streams frames and answers host commands until the next simulated
+// disconnect.
+func (d *device) run(rw io.ReadWriter) error {
+	commands := make(chan protocol.Frame)
+	done := make(chan struct{})
+	defer close(done)
+	go listen(rw, commands, done)
+
+	var unplug <-chan time.Time
+	if d.faults.Every > 0 {
+		unplug = time.After(d.faults.Every)
+	}
+	ticker := time.NewTicker(d.interval())
+	defer ticker.Stop()
+
+	for {
+		if err := d.sample(rw); err != nil {
+			return err
+		}
+	wait:
+		for {
+			select {
+			case <-ticker.C:
+				break wait
+			case cmd := <-commands:
+				if err := d.command(rw, cmd); err != nil {
+					return err
+				}
+				ticker.Reset(d.interval())
+			case <-unplug:
+				return nil
+			}
+		}
+	}
+}
+
+// sample writes one sample of the active channels, preceded by info and
+// config frames as often as the firmware sends them.
+func (d *device) sample(w io.Writer) error {
+	if d.samples%infoInterval == 0 {
+		if err := d.announce(w); err != nil {
+			return err
+		}
+	}
+	d.samples++
+
+	// Stamp and sample exactly like the firmware
+	elapsed := time.Since(d.boot)
+	words := make([]uint16, 3, 3+pins)
+	words[0], words[1] = protocol.TickWords(uint32(elapsed.Milliseconds()))
+	words[2] = d.config.Channels
+	for _, channel := range protocol.ChannelList(d.config.Channels) {
+		var sum uint32
+		for k := uint16(0); k < d.config.Averaging; k++ {
+			sum += uint32(counts(d.wave(elapsed.Seconds(), channel), d.reference, d.bits))
+		}
+		words = append(words, uint16(sum/uint32(d.config.Averaging)))
+	}
+	return d.send(w, protocol.TypeChannels, words)
+}
+
+// announce sends the firmware version and sampling settings.
+func (d *device) announce(w io.Writer) error {
+	if err := d.send(w, protocol.TypeInfo, firmwareVersion); err != nil {
+		return err
+	}
+	settings := d.config.Words()
+	return d.send(w, protocol.TypeConfig, settings[:])
+}
+
+// command answers a frame sent by the host.
+func (d *device) command(w io.Writer, cmd protocol.Frame) error {
+	switch cmd.Type {
+	case protocol.TypeQuery:
+		return d.announce(w)
+	case protocol.TypeConfigure:
+		update, _ := protocol.ParseConfig(cmd)
+		d.config = clamp(d.config.Merge(update))
+		log.Printf("configured channels %v every %dms averaging %d", protocol.ChannelList(d.config.Channels), d.config.Interval, d.config.Averaging)
+		settings := d.config.Words()
+		return d.send(w, protocol.TypeConfig, settings[:])
+	}
+	return nil
+}
+
+// interval returns the configured time between samples.
+func (d *device) interval() time.Duration {
+	return time.Duration(d.config.Interval) * time.Millisecond
+}
+
+// clamp keeps a configuration within what the firmware accepts.
+func clamp(c protocol.Config) protocol.Config {
+	c.Channels &= 1<<pins - 1
+	if c.Channels == 0 {
+		c.Channels = 1
+	}
+	if c.Interval < 1 {
+		c.Interval = 1
+	}
+	if c.Averaging < 1 {
+		c.Averaging = 1
+	}
+	if c.Averaging > maxAveraging {
+		c.Averaging = maxAveraging
+	}
+	return c
+}
+
+// send frames words with the next sequence number.
+func (d *device) send(w io.Writer, typ byte, words []uint16) error {
+	d.frame, _ = protocol.AppendFrame(d.frame[:0], typ, d.seq, words)
+	d.seq++
+	return d.write(w, d.frame)
+}
+
+// listen decodes host commands until the pty is closed.
+func listen(r io.Reader, commands chan<- protocol.Frame, done <-chan struct{}) {
+	var scanner protocol.Scanner
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		for _, b := range buf[:n] {
+			cmd, ok := scanner.Feed(b)
+			if !ok {
+				continue
+			}
+			cmd.Words = append([]uint16(nil), cmd.Words...)
+			select {
+			case commands <- cmd:
+			case <-done:
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// write sends one frame, applying the configured faults.
+func (d *device) write(w io.Writer, frame []byte) error {
+	var out []byte
+	if d.rng.Float64() < d.faults.Garbage {
+		for n := 1 + d.rng.Intn(16); n > 0; n-- {
+			out = append(out, byte(d.rng.Intn(256)))
+		}
+	}
+	for _, b := range frame {
+		if d.rng.Float64() < d.faults.Drop {
+			continue
+		}
+		out = append(out, b)
+	}
+	_, err := w.Write(out)
+	return err
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// waveform returns the voltage on a channel t seconds after boot.
+type waveform func(t float64, channel int) float64
+
+// waveforms lists the kinds accepted by newWaveform.
+var waveforms = []string{"constant", "sine", "noise", "step"}
+
+// newWaveform builds a synthetic signal around offset volts. Channels are a
+// quarter period apart so they can be told apart in a recording.
+func newWaveform(kind string, offset, amplitude, period float64, rng *rand.Rand) (waveform, error) {
+	switch kind {
+	case "constant":
+		return func(t float64, channel int) float64 {
+			return offset
+		}, nil
+	case "sine":
+		return func(t float64, channel int) float64 {
+			return offset + amplitude*math.Sin(2*math.Pi*t/period+float64(channel)*math.Pi/2)
+		}, nil
+	case "noise":
+		return func(t float64, channel int) float64 {
+			return offset + amplitude*rng.NormFloat64()
+		}, nil
+	case "step":
+		return func(t float64, channel int) float64 {
+			phase := math.Mod(t/period+float64(channel)/4, 1)
+			if phase < 0.5 {
+				return offset
+			}
+			return offset + amplitude
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown waveform %q, want one of %v", kind, waveforms)
+}
+
+// counts converts a voltage to the reading an ADC of the given resolution
+// reports, clamping to the rails like the real converter.
+func counts(v, reference float64, bits int) uint16 {
+	full := float64(uint32(1)<<uint(bits) - 1)
+	c := math.Round(v / reference * full)
+	return uint16(math.Max(0, math.Min(full, c)))
+}
@@ -0,0 +1,145 @@
+package acquire
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// The binary recording format keeps multi-day captures small. A file
+// starts with the magic "VLOG", a version byte and a uint16 length followed
+// by that many bytes of preamble, the same "key: value" lines as the CSV
+// preamble separated by newlines. Each reading follows as:
+//
+//	int64   host time, Unix nanoseconds
+//	uint16  sequence number
+//	uint32  device ticks
+//	uint8   flags, bit 0 set when the ticks are valid
+//	uint8   channel count n
+//	n × (uint8 pin, uint16 raw counts, float32 volts)
+//
+// All values are little-endian.
+const (
+	binaryMagic   = "VLOG"
+	binaryVersion = 1
+	flagTimed     = 1
+)
+
+// BinaryWriter writes readings in the binary recording format.
+type BinaryWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewBinaryWriter writes the file header with the given preamble lines.
+func NewBinaryWriter(w io.Writer, preamble []string) (*BinaryWriter, error) {
+	text := strings.Join(preamble, "\n")
+	if len(text) > math.MaxUint16 {
+		return nil, fmt.Errorf("preamble of %d bytes is too long", len(text))
+	}
+	header := append([]byte(binaryMagic), binaryVersion)
+	header = binary.LittleEndian.AppendUint16(header, uint16(len(text)))
+	header = append(header, text...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &BinaryWriter{w: w}, nil
+}
+
+// Write appends one reading.
+func (b *BinaryWriter) Write(r Reading) error {
+	if len(r.Channels) > math.MaxUint8 {
+		return fmt.Errorf("reading has %d channels", len(r.Channels))
+	}
+	buf := binary.LittleEndian.AppendUint64(b.buf[:0], uint64(r.Received.UnixNano()))
+	buf = binary.LittleEndian.AppendUint16(buf, r.Seq)
+	buf = binary.LittleEndian.AppendUint32(buf, r.Ticks)
+	var flags byte
+	if r.Timed {
+		flags |= flagTimed
+	}
+	buf = append(buf, flags, byte(len(r.Channels)))
+	for i, pin := range r.Channels {
+		var counts uint16
+		if i < len(r.Counts) {
+			counts = r.Counts[i]
+		}
+		buf = append(buf, byte(pin))
+		buf = binary.LittleEndian.AppendUint16(buf, counts)
+		buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(float32(r.Volts[i])))
+	}
+	b.buf = buf
+	_, err := b.w.Write(buf)
+	return err
+}
+
+// isBinary reports whether a recording starts with the binary magic.
+func isBinary(br *bufio.Reader) bool {
+	b, err := br.Peek(len(binaryMagic))
+	return err == nil && bytes.Equal(b, []byte(binaryMagic))
+}
+
+// binaryReader reads readings back from the binary format.
+type binaryReader struct {
+	r        io.Reader
+	preamble []string
+}
+
+// newBinaryReader checks the header and reads the preamble.
+func newBinaryReader(r io.Reader) (*binaryReader, error) {
+	header := make([]byte, len(binaryMagic)+3)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if string(header[:len(binaryMagic)]) != binaryMagic {
+		return nil, errors.New("not a binary recording")
+	}
+	if v := header[len(binaryMagic)]; v != binaryVersion {
+		return nil, fmt.Errorf("binary recording version %d, want %d", v, binaryVersion)
+	}
+	text := make([]byte, binary.LittleEndian.Uint16(header[len(binaryMagic)+1:]))
+	if _, err := io.ReadFull(r, text); err != nil {
+		return nil, err
+	}
+	br := &binaryReader{r: r}
+	if len(text) > 0 {
+		br.preamble = strings.Split(string(text), "\n")
+	}
+	return br, nil
+}
+
+// next reads one reading, returning io.EOF at a clean end of file and
+// io.ErrUnexpectedEOF when the last reading was cut short.
+func (b *binaryReader) next() (Reading, error) {
+	var head [16]byte
+	if _, err := io.ReadFull(b.r, head[:]); err != nil {
+		return Reading{}, err
+	}
+	r := Reading{
+		Received: time.Unix(0, int64(binary.LittleEndian.Uint64(head[0:]))),
+		Seq:      binary.LittleEndian.Uint16(head[8:]),
+		Ticks:    binary.LittleEndian.Uint32(head[10:]),
+		Timed:    head[14]&flagTimed != 0,
+	}
+	n := int(head[15])
+	body := make([]byte, 7*n)
+	if _, err := io.ReadFull(b.r, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return Reading{}, err
+	}
+	for i := 0; i < n; i++ {
+		c := body[7*i:]
+		r.Channels = append(r.Channels, int(c[0]))
+		r.Counts = append(r.Counts, binary.LittleEndian.Uint16(c[1:]))
+		r.Volts = append(r.Volts, float64(math.Float32frombits(binary.LittleEndian.Uint32(c[3:]))))
+	}
+	return r, nil
+}
@@ -0,0 +1,126 @@
+// Package acquire connects to the voltmeter over a serial port. It finds
+// the board, keeps the connection alive across unplugs and turns frames into
+// calibrated readings for the logger, the live plot and anything else that
+// consumes voltages.
+package acquire
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jacobsa/go-serial/serial"
+
+	"voltmeter/protocol"
+)
+
+// Config selects and configures the serial port and the firmware's
+// sampling. Data bits are fixed at eight because the frames are binary.
+type Config struct {
+	Port        string   `json:"port"` // device path, or "auto" to scan for the board
+	Baud        uint     `json:"baud"`
+	Parity      string   `json:"parity"` // none, odd or even
+	StopBits    uint     `json:"stop_bits"`
+	ReadTimeout Duration `json:"read_timeout"` // zero blocks until data arrives
+	Scan        []string `json:"scan"`         // patterns searched by auto-detection
+
+	// Sampling requested from the firmware on every connection; unset
+	// fields keep the board's current setting
+	Channels  []int    `json:"channels"`  // ADC pins to read
+	Interval  Duration `json:"interval"`  // time between samples
+	Averaging int      `json:"averaging"` // readings averaged per sample
+}
+
+// Duration reads as a Go Duration string such as "1s" in config files.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	*d = Duration(v)
+	return err
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Port:        "auto",
+		Baud:        9600,
+		Parity:      "none",
+		StopBits:    1,
+		ReadTimeout: Duration(time.Second),
+		Scan:        []string{"/dev/ttyACM*", "/dev/ttyUSB*"},
+	}
+}
+
+// LoadConfig reads a JSON config file over the defaults.
+func LoadConfig(path string) (Config, error) {
+	c := DefaultConfig()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Options translates the config into go-serial options for a port.
+func (c Config) Options(name string) (serial.OpenOptions, error) {
+	o := serial.OpenOptions{
+		PortName:        name,
+		BaudRate:        c.Baud,
+		DataBits:        8,
+		StopBits:        c.StopBits,
+		MinimumReadSize: 1,
+	}
+	switch c.Parity {
+	case "none":
+		o.ParityMode = serial.PARITY_NONE
+	case "odd":
+		o.ParityMode = serial.PARITY_ODD
+	case "even":
+		o.ParityMode = serial.PARITY_EVEN
+	default:
+		return o, fmt.Errorf("unknown parity %q, want none, odd or even", c.Parity)
+	}
+	if c.StopBits != 1 && c.StopBits != 2 {
+		return o, fmt.Errorf("stop bits must be 1 or 2, got %d", c.StopBits)
+	}
+
+	// The terminal driver counts timeouts in tenths of a second
+	if timeout := time.Duration(c.ReadTimeout); timeout > 0 {
+		if timeout < 100*time.Millisecond || timeout > 25500*time.Millisecond {
+			return o, fmt.Errorf("read timeout must be between 100ms and 25.5s, got %v", timeout)
+		}
+		o.MinimumReadSize = 0
+		o.InterCharacterTimeout = uint(timeout / time.Millisecond)
+	}
+	return o, nil
+}
+
+// Sampling translates the requested sampling into a configure payload.
+func (c Config) Sampling() (protocol.Config, error) {
+	var s protocol.Config
+	for _, pin := range c.Channels {
+		if pin < 0 || pin >= protocol.BoardChannels {
+			return s, fmt.Errorf("channel %d out of range 0 to %d", pin, protocol.BoardChannels-1)
+		}
+		s.Channels |= 1 << uint(pin)
+	}
+	if interval := time.Duration(c.Interval); interval != 0 {
+		if interval < time.Millisecond || interval > 65535*time.Millisecond {
+			return s, fmt.Errorf("interval must be between 1ms and 65.535s, got %v", interval)
+		}
+		s.Interval = uint16(interval / time.Millisecond)
+	}
+	if c.Averaging < 0 || c.Averaging > protocol.MaxAveraging {
+		return s, fmt.Errorf("averaging must be between 1 and %d, or 0 to keep the board's setting, got %d", protocol.MaxAveraging, c.Averaging)
+	}
+	s.Averaging = uint16(c.Averaging)
+	return s, nil
+}
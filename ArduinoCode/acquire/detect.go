@@ -0,0 +1,93 @@
+package acquire
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jacobsa/go-serial/serial"
+
+	"voltmeter/protocol"
+)
+
+// How long a port has to answer the handshake. Opening the port resets most
+// boards, so this covers the bootloader as well as one sample period.
+const handshakeTimeout = 4 * time.Second
+
+// Detect returns the first port matching the scan patterns whose device
+// answers the handshake.
+func Detect(c Config) (string, error) {
+	for _, pattern := range c.Scan {
+		names, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", err
+		}
+		for _, name := range names {
+			if _, err := handshake(c, name); err != nil {
+				log.Printf("%s: %v", name, err)
+				continue
+			}
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no voltmeter found on %s", strings.Join(c.Scan, ", "))
+}
+
+// handshake queries a port and waits for the firmware's info frame.
+func handshake(c Config, name string) (protocol.Frame, error) {
+	c.ReadTimeout = Duration(200 * time.Millisecond)
+	opts, err := c.Options(name)
+	if err != nil {
+		return protocol.Frame{}, err
+	}
+	port, err := serial.Open(opts)
+	if err != nil {
+		return protocol.Frame{}, err
+	}
+	defer port.Close()
+
+	query, _ := protocol.AppendFrame(nil, protocol.TypeQuery, 0, nil)
+	deadline := time.Now().Add(handshakeTimeout)
+	decoder := protocol.NewDecoder(deadlineReader{port, deadline})
+	var asked time.Time
+	for time.Now().Before(deadline) {
+		// Repeat the query in case the board was still booting
+		if time.Since(asked) > time.Second {
+			if _, err := port.Write(query); err != nil {
+				return protocol.Frame{}, err
+			}
+			asked = time.Now()
+		}
+		f, err := decoder.Next()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return protocol.Frame{}, err
+		}
+		if f.Type == protocol.TypeInfo {
+			return f, nil
+		}
+	}
+	return protocol.Frame{}, errNoAnswer
+}
+
+var errNoAnswer = errors.New("no answer to handshake")
+
+// deadlineReader fails reads once the deadline has passed, so a chatty
+// device that never sends a valid frame cannot stall detection.
+type deadlineReader struct {
+	r        io.Reader
+	deadline time.Time
+}
+
+func (d deadlineReader) Read(p []byte) (int, error) {
+	if time.Now().After(d.deadline) {
+		return 0, errNoAnswer
+	}
+	return d.r.Read(p)
+}
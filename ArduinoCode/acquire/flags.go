@@ -0,0 +1,169 @@
+package acquire
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"voltmeter/calibration"
+)
+
+// Flags are the command line options shared by every program that reads
+// the voltmeter: calibration, serial port and sampling settings.
+type Flags struct {
+	fs *flag.FlagSet
+
+	calibration *string
+	reference   *float64
+	resolution  *int
+
+	config    *string
+	port      *string
+	baud      *uint
+	parity    *string
+	stopBits  *uint
+	timeout   *time.Duration
+	scan      *string
+	channels  *string
+	interval  *time.Duration
+	averaging *int
+
+	replay *string
+	speed  *float64
+	step   *bool
+}
+
+// RegisterFlags defines the shared flags on fs.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	defaults := DefaultConfig()
+	return &Flags{
+		fs:          fs,
+		calibration: fs.String("calibration", "", "JSON calibration file with reference voltage, resolution and per-channel polynomials"),
+		reference:   fs.Float64("vref", 0, "ADC reference voltage in volts (overrides the calibration file)"),
+		resolution:  fs.Int("bits", 0, "ADC resolution in bits: 10, 12, or 16 for TinyGo's scaled readings (overrides the calibration file)"),
+		config:      fs.String("config", "", "JSON file with serial port and sampling settings, overridden by the flags below"),
+		port:        fs.String("port", defaults.Port, `serial device, or "auto" to scan for the voltmeter`),
+		baud:        fs.Uint("baud", defaults.Baud, "baud rate"),
+		parity:      fs.String("parity", defaults.Parity, "parity: none, odd or even"),
+		stopBits:    fs.Uint("stop", defaults.StopBits, "stop bits: 1 or 2"),
+		timeout:     fs.Duration("timeout", time.Duration(defaults.ReadTimeout), "read timeout, 0 blocks until data arrives"),
+		scan:        fs.String("scan", strings.Join(defaults.Scan, ","), "comma separated patterns searched when -port is auto"),
+		channels:    fs.String("channels", "", "comma separated ADC pins 0 to 5 to sample, e.g. 0,1,2 (default: keep the board's setting)"),
+		interval:    fs.Duration("interval", 0, "time between samples, at least 1ms (default: keep the board's setting)"),
+		averaging:   fs.Int("average", 0, "readings averaged into each sample, at most 64 (default: keep the board's setting)"),
+		replay:      fs.String("replay", "", "replay a recording from the logger instead of reading the board"),
+		speed:       fs.Float64("speed", 1, "replay speed: 1 is the recorded rate, 10 ten times faster, 0 as fast as possible"),
+		step:        fs.Bool("step", false, "replay one reading each time Enter is pressed"),
+	}
+}
+
+// Load builds the calibration and config once the flags have been parsed.
+// Files are read first and flags given on the command line override them.
+func (f *Flags) Load() (Config, calibration.Calibration, error) {
+	// Work out how counts become volts
+	cal := calibration.Default()
+	if *f.calibration != "" {
+		var err error
+		cal, err = calibration.Load(*f.calibration)
+		if err != nil {
+			return Config{}, cal, err
+		}
+	}
+	if *f.reference != 0 {
+		cal.Reference = *f.reference
+	}
+	if *f.resolution != 0 {
+		cal.Resolution = *f.resolution
+	}
+	if err := cal.Validate(); err != nil {
+		return Config{}, cal, err
+	}
+
+	// Settings come from the config file, then any flags given
+	config := DefaultConfig()
+	if *f.config != "" {
+		var err error
+		config, err = LoadConfig(*f.config)
+		if err != nil {
+			return config, cal, err
+		}
+	}
+	var err error
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "port":
+			config.Port = *f.port
+		case "baud":
+			config.Baud = *f.baud
+		case "parity":
+			config.Parity = *f.parity
+		case "stop":
+			config.StopBits = *f.stopBits
+		case "timeout":
+			config.ReadTimeout = Duration(*f.timeout)
+		case "scan":
+			config.Scan = strings.Split(*f.scan, ",")
+		case "channels":
+			config.Channels = nil
+			for _, field := range strings.Split(*f.channels, ",") {
+				pin, perr := strconv.Atoi(strings.TrimSpace(field))
+				if perr != nil {
+					err = fmt.Errorf("-channels: %v", perr)
+					return
+				}
+				config.Channels = append(config.Channels, pin)
+			}
+		case "interval":
+			config.Interval = Duration(*f.interval)
+		case "average":
+			config.Averaging = *f.averaging
+		}
+	})
+	if err != nil {
+		return config, cal, err
+	}
+
+	// Reject bad settings now rather than on every reconnect
+	if _, err := config.Options(config.Port); err != nil {
+		return config, cal, err
+	}
+	if _, err := config.Sampling(); err != nil {
+		return config, cal, err
+	}
+	return config, cal, nil
+}
+
+// Open starts the readings selected by the flags: the recording given with
+// -replay, otherwise the live board.
+func (f *Flags) Open(config Config, cal calibration.Calibration) (<-chan Reading, error) {
+	if *f.replay != "" {
+		r := &Replay{Path: *f.replay, Cal: cal, Speed: *f.speed}
+		if *f.step {
+			r.Step = os.Stdin
+			log.Printf("press Enter to step through %s", r.Path)
+		}
+		return r.Readings()
+	}
+	sampling, err := config.Sampling()
+	if err != nil {
+		return nil, err
+	}
+	return Live(NewLink(config, sampling), cal), nil
+}
+
+// Replaying reports whether -replay was given.
+func (f *Flags) Replaying() bool {
+	return *f.replay != ""
+}
+
+// Source describes where Open reads from, for titles and logs.
+func (f *Flags) Source(config Config) string {
+	if *f.replay != "" {
+		return *f.replay
+	}
+	return config.Port
+}
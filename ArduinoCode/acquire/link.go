@@ -0,0 +1,262 @@
+package acquire
+
+import (
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jacobsa/go-serial/serial"
+	"golang.org/x/sys/unix"
+
+	"voltmeter/protocol"
+)
+
+// Reconnect attempts back off from minBackoff, doubling up to maxBackoff.
+const (
+	minBackoff = 500 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+// Summary counts what happened on a link over all its connections.
+type Summary struct {
+	protocol.Stats
+	Reconnects int
+	Errors     int // read and open failures
+}
+
+// Link keeps a connection to the voltmeter open, reconnecting with backoff
+// whenever the board is unplugged or the port fails. Next runs on one
+// goroutine; Summary and Configure may be called from any.
+type Link struct {
+	config  Config
+	auto    bool // rerun detection on every reconnect
+	name    string
+	decoder *protocol.Decoder
+	stale   bool // samples may predate the requested settings
+
+	wmu       sync.Mutex // guards port, hello and requested, which Configure also uses
+	port      io.ReadWriteCloser
+	hello     []byte          // query and configure frames sent on every connection
+	requested protocol.Config // settings asked for, zero fields left as the board has them
+
+	previous   protocol.Stats // decoder counters from closed connections
+	reconnects int
+	errors     int
+
+	mu      sync.Mutex
+	summary Summary // published copy of the counters
+}
+
+// NewLink prepares a link; sampling holds the settings to request from the
+// firmware, with zero fields left as the board has them.
+func NewLink(config Config, sampling protocol.Config) *Link {
+	l := &Link{config: config, auto: config.Port == "auto", name: config.Port, requested: sampling}
+	l.hello, _ = protocol.AppendFrame(nil, protocol.TypeQuery, 0, nil)
+	if sampling != (protocol.Config{}) {
+		settings := sampling.Words()
+		l.hello, _ = protocol.AppendFrame(l.hello, protocol.TypeConfigure, 1, settings[:])
+	}
+	return l
+}
+
+// connect opens the port, detecting the board first in auto mode, then asks
+// the firmware for its version and settings and applies any requested ones.
+func (l *Link) connect() error {
+	if l.auto {
+		name, err := Detect(l.config)
+		if err != nil {
+			return err
+		}
+		log.Printf("found voltmeter on %s", name)
+		l.name = name
+	}
+	options, err := l.config.Options(l.name)
+	if err != nil {
+		return err
+	}
+	port, err := serial.Open(options)
+	if err != nil {
+		return err
+	}
+	// Bytes queued before the port was opened predate our settings
+	if f, ok := port.(interface{ Fd() uintptr }); ok {
+		unix.IoctlSetInt(int(f.Fd()), unix.TCFLSH, unix.TCIFLUSH)
+	}
+	l.wmu.Lock()
+	defer l.wmu.Unlock()
+	l.stale = l.requested != protocol.Config{}
+	if _, err := port.Write(l.hello); err != nil {
+		port.Close()
+		return err
+	}
+	l.port = port
+	l.decoder = protocol.NewDecoder(port)
+	return nil
+}
+
+// Configure asks the firmware for new sampling settings, now if connected
+// and again on every reconnect. Zero fields keep the board's values.
+// Readings already in flight at the old settings are still delivered.
+func (l *Link) Configure(sampling protocol.Config) error {
+	settings := sampling.Words()
+	hello, _ := protocol.AppendFrame(nil, protocol.TypeQuery, 0, nil)
+	hello, _ = protocol.AppendFrame(hello, protocol.TypeConfigure, 1, settings[:])
+
+	l.wmu.Lock()
+	defer l.wmu.Unlock()
+	l.hello = hello
+	l.requested = sampling
+	if l.port == nil {
+		return nil
+	}
+	_, err := l.port.Write(hello[protocol.FrameSize(0):])
+	return err
+}
+
+// reconnect closes the port and retries until it opens again.
+func (l *Link) reconnect(reason error) {
+	log.Printf("lost %s: %v", l.name, reason)
+	l.disconnect()
+	l.reconnects++
+	l.dial()
+}
+
+// dial retries connect with exponential backoff.
+func (l *Link) dial() {
+	backoff := minBackoff
+	for {
+		err := l.connect()
+		if err == nil {
+			l.publish()
+			return
+		}
+		l.errors++
+		l.publish()
+		log.Printf("connecting: %v, retrying in %v", err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// disconnect closes the port, keeping the decoder's counters.
+func (l *Link) disconnect() {
+	if l.port == nil {
+		return
+	}
+	l.previous = l.Stats()
+	l.wmu.Lock()
+	l.port.Close()
+	l.port, l.decoder = nil, nil
+	l.wmu.Unlock()
+}
+
+// Next blocks until a frame arrives. A read that times out or hits the end
+// of the stream only means no data yet, unless the device itself has gone.
+func (l *Link) Next() protocol.Frame {
+	for {
+		if l.port == nil {
+			l.dial()
+			continue
+		}
+		f, err := l.decoder.Next()
+		l.publish()
+		switch {
+		case err == nil && l.stale && f.Type != protocol.TypeConfig && f.Type != protocol.TypeInfo:
+			// Sampled before the board applied our settings
+		case err == nil:
+			if l.stale && f.Type == protocol.TypeConfig && l.applied(f) {
+				l.stale = false
+			}
+			return f
+		case err == io.EOF && l.config.ReadTimeout > 0 && l.present():
+			// Timed out waiting for data
+		default:
+			l.errors++
+			l.reconnect(err)
+		}
+	}
+}
+
+// applied reports whether a config frame shows the requested settings in
+// use. The firmware announces its settings unprompted and answers the
+// query before the configure, so a config frame alone may still be the old
+// settings.
+func (l *Link) applied(f protocol.Frame) bool {
+	c, ok := protocol.ParseConfig(f)
+	if !ok {
+		return false
+	}
+	l.wmu.Lock()
+	defer l.wmu.Unlock()
+	return c.Merge(l.requested) == c
+}
+
+// present reports whether the device node still exists.
+func (l *Link) present() bool {
+	_, err := os.Stat(l.name)
+	return err == nil
+}
+
+// Summary returns the counters as of the last frame or failure.
+func (l *Link) Summary() Summary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.summary
+}
+
+// publish copies the counters for Summary.
+func (l *Link) publish() {
+	l.mu.Lock()
+	l.summary = Summary{Stats: l.Stats(), Reconnects: l.reconnects, Errors: l.errors}
+	l.mu.Unlock()
+}
+
+// Stats returns the decoder counters over every connection.
+func (l *Link) Stats() protocol.Stats {
+	s := l.previous
+	if l.decoder != nil {
+		d := l.decoder.Stats()
+		s.Frames += d.Frames
+		s.Dropped += d.Dropped
+		s.Corrupt += d.Corrupt
+		s.Skipped += d.Skipped
+		s.Restarts += d.Restarts
+	}
+	return s
+}
+
+// Name returns the device the link is using, or last used.
+func (l *Link) Name() string {
+	return l.name
+}
+
+// Close releases the port.
+func (l *Link) Close() {
+	l.disconnect()
+}
+
+// Arrival is a frame with the time and port it was received on.
+type Arrival struct {
+	Frame    protocol.Frame
+	Received time.Time
+	Port     string
+}
+
+// Stream calls Next on a new goroutine and delivers every frame on the
+// returned channel, so a caller can wait on it alongside signals or a
+// render loop. The goroutine runs for the life of the program.
+func (l *Link) Stream() <-chan Arrival {
+	arrivals := make(chan Arrival)
+	go func() {
+		for {
+			f := l.Next()
+			arrivals <- Arrival{Frame: f, Received: time.Now(), Port: l.name}
+		}
+	}()
+	return arrivals
+}
@@ -0,0 +1,69 @@
+package acquire
+
+import (
+	"time"
+
+	"voltmeter/calibration"
+	"voltmeter/protocol"
+)
+
+// Reading is one sample of the active channels converted to volts.
+type Reading struct {
+	Received time.Time // host time the frame arrived
+	Seq      uint16
+	Ticks    uint32 // device milliseconds since boot, valid when Timed
+	Timed    bool
+	Channels []int // ADC pin of each value
+	Counts   []uint16
+	Volts    []float64
+}
+
+// Read converts a sample frame into a reading. ok is false for frames that
+// carry no samples or whose readings do not match their channel mask.
+func Read(f protocol.Frame, received time.Time, cal calibration.Calibration) (r Reading, ok bool) {
+	s, ok := f.Samples()
+	if !ok {
+		return r, false
+	}
+	pins := protocol.ChannelList(s.Channels)
+	if len(pins) == 0 || len(pins) != len(s.Readings) {
+		return r, false
+	}
+	r = Reading{
+		Received: received,
+		Seq:      f.Seq,
+		Ticks:    s.Ticks,
+		Timed:    s.Timed,
+		Channels: pins,
+		Counts:   s.Readings,
+		Volts:    make([]float64, len(pins)),
+	}
+	for i, pin := range pins {
+		r.Volts[i] = cal.Volts(pin, s.Readings[i])
+	}
+	return r, true
+}
+
+// Volt returns the voltage on an ADC pin and whether it was sampled.
+func (r Reading) Volt(pin int) (float64, bool) {
+	for i, p := range r.Channels {
+		if p == pin {
+			return r.Volts[i], true
+		}
+	}
+	return 0, false
+}
+
+// Live converts the frames arriving on a link into readings, skipping
+// frames that carry none.
+func Live(l *Link, cal calibration.Calibration) <-chan Reading {
+	readings := make(chan Reading)
+	go func() {
+		for a := range l.Stream() {
+			if r, ok := Read(a.Frame, a.Received, cal); ok {
+				readings <- r
+			}
+		}
+	}()
+	return readings
+}
@@ -0,0 +1,293 @@
+package acquire
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"voltmeter/calibration"
+)
+
+// Replay streams the readings of a recording made by the logger, as if they
+// were arriving from the board. Rows keep their recorded timestamps. Both
+// the CSV and binary formats are read, either of them gzipped when the
+// path ends in ".gz".
+//
+// Recordings from before timestamps were added have a "Timestamp" header
+// but only one raw ADC count per channel in each row; those counts go
+// through the calibration and the rows are spaced a second apart, the rate
+// the old firmware sampled at.
+type Replay struct {
+	Path  string
+	Cal   calibration.Calibration
+	Speed float64   // 1 plays at the recorded rate, 0 as fast as possible
+	Step  io.Reader // when set, each reading waits for a line from it
+}
+
+// legacyInterval is the sampling period of the firmware that wrote
+// recordings without timestamps.
+const legacyInterval = time.Second
+
+// column patterns in a recording's header
+var voltageColumn = regexp.MustCompile(`^Voltage on (.+) \(V\)$`)
+
+// recording describes the layout of the file being replayed.
+type recording struct {
+	started   time.Time
+	timestamp int // column index, -1 when absent
+	elapsed   int
+	ticks     int
+	voltages  []int // column of each channel
+	pins      []int
+}
+
+// Readings opens the recording and returns a channel delivering its
+// readings, closed at the end of the file. Problems with the file itself are
+// reported before streaming starts; unreadable rows are logged and skipped.
+func (r *Replay) Readings() (<-chan Reading, error) {
+	file, err := os.Open(r.Path)
+	if err != nil {
+		return nil, err
+	}
+	var src io.Reader = file
+	if strings.HasSuffix(r.Path, ".gz") {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%s: %v", r.Path, err)
+		}
+		src = zr
+	}
+	br := bufio.NewReader(src)
+	var next func() (Reading, error)
+	if isBinary(br) {
+		next, err = r.binary(br)
+	} else {
+		next, err = r.csv(br)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %v", r.Path, err)
+	}
+
+	readings := make(chan Reading)
+	go func() {
+		defer file.Close()
+		defer close(readings)
+		r.play(next, readings)
+	}()
+	return readings, nil
+}
+
+// csv reads the preamble and header of a CSV recording and returns a
+// function reading its rows in turn, skipping rows it cannot parse. Any
+// other error, such as a truncated file, ends the replay.
+func (r *Replay) csv(br *bufio.Reader) (func() (Reading, error), error) {
+	rec, err := r.preamble(br)
+	if err != nil {
+		return nil, err
+	}
+	rows := csv.NewReader(br)
+	rows.FieldsPerRecord = -1
+	header, err := rows.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %v", err)
+	}
+	if err := rec.layout(header, r.Cal); err != nil {
+		return nil, err
+	}
+	start := rec.started
+	if start.IsZero() {
+		start = time.Now()
+	}
+
+	n := 0
+	return func() (Reading, error) {
+		for ; ; n++ {
+			row, err := rows.Read()
+			if perr, ok := err.(*csv.ParseError); ok {
+				log.Printf("%s: %v", r.Path, perr)
+				continue
+			}
+			if err != nil {
+				return Reading{}, err
+			}
+			reading, err := rec.parse(row, n, start, r.Cal)
+			if err != nil {
+				log.Printf("%s: row %d: %v", r.Path, n+1, err)
+				continue
+			}
+			n++
+			return reading, nil
+		}
+	}, nil
+}
+
+// binary reads the header of a binary recording and returns a function
+// reading its readings in turn.
+func (r *Replay) binary(br *bufio.Reader) (func() (Reading, error), error) {
+	b, err := newBinaryReader(br)
+	if err != nil {
+		return nil, err
+	}
+	return b.next, nil
+}
+
+// preamble reads the "# key: value" lines ahead of the header.
+func (r *Replay) preamble(br *bufio.Reader) (*recording, error) {
+	rec := &recording{timestamp: -1, elapsed: -1, ticks: -1}
+	for {
+		b, err := br.Peek(1)
+		if err != nil || b[0] != '#' {
+			return rec, nil
+		}
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		key, value, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(line, "#")), ": ")
+		switch {
+		case key == "started":
+			rec.started, _ = time.Parse(time.RFC3339Nano, value)
+		case strings.HasPrefix(key, "calibration"):
+			log.Printf("recorded with %s: %s", key, value)
+		}
+	}
+}
+
+// layout finds the columns of a header row.
+func (rec *recording) layout(header []string, cal calibration.Calibration) error {
+	for i, name := range header {
+		switch name {
+		case "Timestamp":
+			rec.timestamp = i
+		case "Elapsed (s)":
+			rec.elapsed = i
+		case "Device ticks (ms)":
+			rec.ticks = i
+		default:
+			m := voltageColumn.FindStringSubmatch(name)
+			if m == nil {
+				continue
+			}
+			rec.voltages = append(rec.voltages, i)
+			rec.pins = append(rec.pins, pinNamed(m[1], len(rec.pins), cal))
+		}
+	}
+	if len(rec.voltages) == 0 {
+		return fmt.Errorf("no voltage columns in header %q", strings.Join(header, ","))
+	}
+	return nil
+}
+
+// pinNamed recovers the ADC pin of a column from its channel name.
+func pinNamed(name string, index int, cal calibration.Calibration) int {
+	for _, ch := range cal.Channels {
+		if ch.Name == name {
+			return ch.Channel
+		}
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(name, "ADC")); err == nil && strings.HasPrefix(name, "ADC") {
+		return n
+	}
+	return index
+}
+
+// play sends every reading next returns, pacing them by their timestamps.
+func (r *Replay) play(next func() (Reading, error), readings chan<- Reading) {
+	var step *bufio.Reader
+	if r.Step != nil {
+		step = bufio.NewReader(r.Step)
+	}
+	var previous time.Time
+	for {
+		reading, err := next()
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("%s: %v", r.Path, err)
+			}
+			return
+		}
+
+		// Wait as long as the recording did, or for the next step
+		switch {
+		case step != nil:
+			if _, err := step.ReadString('\n'); err != nil {
+				return
+			}
+		case r.Speed > 0 && !previous.IsZero():
+			time.Sleep(time.Duration(float64(reading.Received.Sub(previous)) / r.Speed))
+		}
+		previous = reading.Received
+		readings <- reading
+	}
+}
+
+// parse converts one row. Rows with a value per voltage column only are
+// legacy raw counts.
+func (rec *recording) parse(row []string, n int, start time.Time, cal calibration.Calibration) (Reading, error) {
+	reading := Reading{Seq: uint16(n), Channels: rec.pins}
+	if len(row) == len(rec.voltages) && len(row) < rec.voltages[len(rec.voltages)-1]+1 {
+		reading.Received = start.Add(time.Duration(n) * legacyInterval)
+		for i, field := range row {
+			counts, err := strconv.ParseUint(strings.TrimSpace(field), 10, 16)
+			if err != nil {
+				return reading, err
+			}
+			reading.Counts = append(reading.Counts, uint16(counts))
+			reading.Volts = append(reading.Volts, cal.Volts(rec.pins[i], uint16(counts)))
+		}
+		return reading, nil
+	}
+
+	// Timestamped rows, preferring the absolute time
+	switch {
+	case rec.timestamp >= 0 && rec.timestamp < len(row) && row[rec.timestamp] != "":
+		t, err := time.Parse(time.RFC3339Nano, row[rec.timestamp])
+		if err != nil {
+			return reading, err
+		}
+		reading.Received = t
+	case rec.elapsed >= 0 && rec.elapsed < len(row):
+		s, err := strconv.ParseFloat(row[rec.elapsed], 64)
+		if err != nil {
+			return reading, err
+		}
+		reading.Received = start.Add(time.Duration(s * float64(time.Second)))
+	default:
+		reading.Received = start.Add(time.Duration(n) * legacyInterval)
+	}
+	if rec.ticks >= 0 && rec.ticks < len(row) && row[rec.ticks] != "" {
+		ticks, err := strconv.ParseUint(row[rec.ticks], 10, 32)
+		if err != nil {
+			return reading, err
+		}
+		reading.Ticks, reading.Timed = uint32(ticks), true
+	}
+
+	// Channels left blank were not sampled in this row
+	reading.Channels = nil
+	for i, column := range rec.voltages {
+		if column >= len(row) || row[column] == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(row[column], 64)
+		if err != nil {
+			return reading, err
+		}
+		reading.Channels = append(reading.Channels, rec.pins[i])
+		reading.Volts = append(reading.Volts, v)
+	}
+	if len(reading.Channels) == 0 {
+		return reading, fmt.Errorf("no readings")
+	}
+	return reading, nil
+}
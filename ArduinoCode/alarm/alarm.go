@@ -0,0 +1,270 @@
+// Package alarm watches voltmeter readings for conditions declared per
+// channel and reports when each starts and clears, so unattended
+// experiments can flag problems. What to do about an alarm is up to the
+// caller; rules only carry the actions the user asked for.
+package alarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"voltmeter/acquire"
+	"voltmeter/protocol"
+)
+
+// Conditions a rule can watch for.
+const (
+	Above  = "above"  // the voltage rises over Threshold
+	Below  = "below"  // the voltage falls under Threshold
+	Rate   = "rate"   // the voltage changes faster than Threshold volts per second
+	Stuck  = "stuck"  // the voltage stays within Threshold volts of one value for For
+	Silent = "silent" // no reading arrives on the channel for For
+)
+
+// Actions a rule can ask for when it is raised.
+const (
+	ActionLog     = "log"     // write an event row, also when the alarm clears
+	ActionCapture = "capture" // sample faster for a while
+	ActionCommand = "command" // run a local command
+)
+
+// Rule is one condition on one channel.
+type Rule struct {
+	Name      string  `json:"name"`
+	Channel   int     `json:"channel"`
+	Condition string  `json:"condition"`
+	Threshold float64 `json:"threshold"`
+	// Hysteresis is how far back past the threshold the value must come
+	// before the alarm clears, so noise near it does not flap
+	Hysteresis float64          `json:"hysteresis"`
+	For        acquire.Duration `json:"for"` // how long a stuck or silent condition must last
+	Actions    []Action         `json:"actions"`
+}
+
+// Action is something to do when a rule is raised.
+type Action struct {
+	Kind string `json:"kind"`
+
+	// Capture actions sample every Interval for Duration, then go back to
+	// the settings in force before
+	Interval acquire.Duration `json:"interval"`
+	Duration acquire.Duration `json:"duration"`
+
+	// Command actions run Command, the program followed by its arguments,
+	// with the alarm described in ALARM_* environment variables
+	Command []string `json:"command"`
+}
+
+// Sampling returns the configure payload of a capture action, with the
+// board's channels left as they are and every reading sent unaveraged.
+func (a Action) Sampling() (protocol.Config, error) {
+	return acquire.Config{Interval: a.Interval, Averaging: 1}.Sampling()
+}
+
+// Load reads a JSON array of rules from path and checks them.
+func Load(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for i, r := range rules {
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %v", path, i+1, err)
+		}
+	}
+	return rules, nil
+}
+
+// Validate reports the first problem with a rule.
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("rule has no name")
+	}
+	if r.Channel < 0 || r.Channel > 15 {
+		return fmt.Errorf("%s: channel must be an ADC pin from 0 to 15, got %d", r.Name, r.Channel)
+	}
+	switch r.Condition {
+	case Above, Below:
+	case Rate:
+		if r.Threshold <= 0 {
+			return fmt.Errorf("%s: a rate threshold must be positive", r.Name)
+		}
+	case Stuck:
+		if r.For <= 0 {
+			return fmt.Errorf("%s: a stuck rule needs a positive for", r.Name)
+		}
+	case Silent:
+		if r.For <= 0 {
+			return fmt.Errorf("%s: a silent rule needs a positive for", r.Name)
+		}
+	default:
+		return fmt.Errorf("%s: unknown condition %q", r.Name, r.Condition)
+	}
+	if r.Hysteresis < 0 {
+		return fmt.Errorf("%s: hysteresis must not be negative", r.Name)
+	}
+	for _, a := range r.Actions {
+		switch a.Kind {
+		case ActionLog:
+		case ActionCapture:
+			if a.Interval <= 0 || a.Duration <= 0 {
+				return fmt.Errorf("%s: a capture needs a positive interval and duration", r.Name)
+			}
+			if _, err := a.Sampling(); err != nil {
+				return fmt.Errorf("%s: capture %v", r.Name, err)
+			}
+		case ActionCommand:
+			if len(a.Command) == 0 {
+				return fmt.Errorf("%s: a command action needs a command", r.Name)
+			}
+		default:
+			return fmt.Errorf("%s: unknown action %q", r.Name, a.Kind)
+		}
+	}
+	return nil
+}
+
+// Event is an alarm starting or clearing.
+type Event struct {
+	Rule   *Rule
+	Time   time.Time
+	Raised bool    // true when the condition starts, false when it clears
+	Value  float64 // the voltage, or rate for rate rules, that changed the state
+}
+
+// String describes the event for logs.
+func (e Event) String() string {
+	state := "cleared"
+	if e.Raised {
+		state = "raised"
+	}
+	unit := "V"
+	if e.Rule.Condition == Rate {
+		unit = "V/s"
+	}
+	if e.Rule.Condition == Silent {
+		return fmt.Sprintf("%s %s: %s on ADC%d", e.Rule.Name, state, e.Rule.Condition, e.Rule.Channel)
+	}
+	return fmt.Sprintf("%s %s: %s on ADC%d at %.4f %s", e.Rule.Name, state, e.Rule.Condition, e.Rule.Channel, e.Value, unit)
+}
+
+// watch is the state of one rule.
+type watch struct {
+	rule   Rule
+	active bool
+
+	last     time.Time // time of the channel's last reading
+	previous float64   // its value, for rates
+	since    time.Time // when a stuck value was first seen
+	held     float64   // the value the channel may be stuck at
+}
+
+// Engine checks readings against a set of rules.
+type Engine struct {
+	watches []*watch
+}
+
+// NewEngine watches for rules, which must be valid.
+func NewEngine(rules []Rule) *Engine {
+	e := &Engine{}
+	for _, r := range rules {
+		e.watches = append(e.watches, &watch{rule: r})
+	}
+	return e
+}
+
+// Reading checks a reading and returns the alarms it raised or cleared.
+func (e *Engine) Reading(r acquire.Reading) []Event {
+	var events []Event
+	for _, w := range e.watches {
+		v, ok := r.Volt(w.rule.Channel)
+		if !ok {
+			continue
+		}
+		if ev, changed := w.reading(r.Received, v); changed {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// Tick checks the rules that fire on the absence of readings. Call it
+// regularly, at least as often as the shortest silent rule's For.
+func (e *Engine) Tick(now time.Time) []Event {
+	var events []Event
+	for _, w := range e.watches {
+		if w.rule.Condition != Silent || w.active {
+			continue
+		}
+		// A channel that never reports is timed from the first tick
+		if w.last.IsZero() {
+			w.last = now
+			continue
+		}
+		if now.Sub(w.last) >= time.Duration(w.rule.For) {
+			w.active = true
+			events = append(events, Event{Rule: &w.rule, Time: now, Raised: true})
+		}
+	}
+	return events
+}
+
+// reading updates the rule with a value seen at t.
+func (w *watch) reading(t time.Time, v float64) (Event, bool) {
+	r := &w.rule
+	first := w.last.IsZero()
+	dt := t.Sub(w.last).Seconds()
+	previous := w.previous
+	w.last, w.previous = t, v
+
+	value, raise, clear := v, false, false
+	switch r.Condition {
+	case Above:
+		raise, clear = v > r.Threshold, v < r.Threshold-r.Hysteresis
+	case Below:
+		raise, clear = v < r.Threshold, v > r.Threshold+r.Hysteresis
+	case Rate:
+		if first || dt <= 0 {
+			return Event{}, false
+		}
+		value = math.Abs(v-previous) / dt
+		raise, clear = value > r.Threshold, value < r.Threshold-r.Hysteresis
+	case Stuck:
+		// Restart the clock whenever the value leaves the tolerance band
+		if first || math.Abs(v-w.held) > r.Threshold+r.Hysteresis || (!w.active && math.Abs(v-w.held) > r.Threshold) {
+			w.held, w.since = v, t
+			clear = true
+		}
+		raise = t.Sub(w.since) >= time.Duration(r.For)
+	case Silent:
+		raise, clear = false, true
+	}
+
+	switch {
+	case !w.active && raise:
+		w.active = true
+	case w.active && clear:
+		w.active = false
+	default:
+		return Event{}, false
+	}
+	return Event{Rule: r, Time: t, Raised: w.active, Value: value}, true
+}
+
+// Active returns the rules currently raised.
+func (e *Engine) Active() []*Rule {
+	var active []*Rule
+	for _, w := range e.watches {
+		if w.active {
+			active = append(active, &w.rule)
+		}
+	}
+	return active
+}
@@ -0,0 +1,202 @@
+//go:build !tinygo
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"voltmeter/acquire"
+	"voltmeter/alarm"
+	"voltmeter/calibration"
+	"voltmeter/protocol"
+)
+
+// alarms carries out the actions of the rules the engine raises.
+type alarms struct {
+	engine *alarm.Engine
+	link   *acquire.Link
+	name   func(int) string // channel names for the event log
+	path   string           // event log, opened on the first event that asks for it
+	events *eventLog        // nil until then
+
+	// Captures sample faster until captureEnd, then return to normal, the
+	// settings the board reported before. None start until the board has
+	// reported them. After restoring, reports are ignored until the board
+	// confirms normal again.
+	normal     protocol.Config
+	known      bool // normal has been reported
+	capturing  bool
+	restoring  bool
+	captureEnd time.Time
+}
+
+func newAlarms(rules []alarm.Rule, link *acquire.Link, cal calibration.Calibration, path string) *alarms {
+	return &alarms{engine: alarm.NewEngine(rules), link: link, name: cal.ChannelName, path: path}
+}
+
+// reading checks a reading against the rules.
+func (a *alarms) reading(r acquire.Reading) {
+	a.handle(a.engine.Reading(r))
+}
+
+// tick checks for silent channels and ends captures that are over.
+func (a *alarms) tick(now time.Time) {
+	a.handle(a.engine.Tick(now))
+	if a.capturing && !now.Before(a.captureEnd) {
+		a.capturing, a.restoring = false, true
+		log.Printf("capture over, returning to every %d ms", a.normal.Interval)
+		if err := a.link.Configure(a.normal); err != nil {
+			log.Printf("restoring sampling: %v", err)
+		}
+	}
+}
+
+// config notes the sampling settings the board reports outside captures.
+func (a *alarms) config(f protocol.Frame) {
+	c, ok := protocol.ParseConfig(f)
+	switch {
+	case !ok || a.capturing:
+	case a.restoring:
+		a.restoring = c != a.normal
+	default:
+		a.normal, a.known = c, true
+	}
+}
+
+// handle logs each event and runs its rule's actions.
+func (a *alarms) handle(events []alarm.Event) {
+	for _, e := range events {
+		log.Printf("alarm %v", e)
+		for _, action := range e.Rule.Actions {
+			var err error
+			switch {
+			case action.Kind == alarm.ActionLog:
+				err = a.log(e)
+			case !e.Raised:
+				// Only the event log hears about alarms clearing
+			case action.Kind == alarm.ActionCapture:
+				err = a.capture(e.Time, action)
+			case action.Kind == alarm.ActionCommand:
+				err = run(e, action.Command)
+			}
+			if err != nil {
+				log.Printf("alarm %s: %s: %v", e.Rule.Name, action.Kind, err)
+			}
+		}
+	}
+}
+
+// log appends an event row.
+func (a *alarms) log(e alarm.Event) error {
+	if a.events == nil {
+		events, err := openEventLog(a.path)
+		if err != nil {
+			return err
+		}
+		a.events = events
+	}
+	return a.events.write(e, a.name(e.Rule.Channel))
+}
+
+// capture samples every interval for the action's duration, extending a
+// capture already under way.
+func (a *alarms) capture(now time.Time, action alarm.Action) error {
+	if end := now.Add(time.Duration(action.Duration)); end.After(a.captureEnd) {
+		a.captureEnd = end
+	}
+	if a.capturing {
+		return nil
+	}
+	if !a.known {
+		return fmt.Errorf("the board has not reported its sampling settings to return to")
+	}
+	fast, err := action.Sampling()
+	if err != nil {
+		return err
+	}
+	a.capturing = true
+	log.Printf("capturing every %d ms until %s", fast.Interval, a.captureEnd.Format(time.TimeOnly))
+	return a.link.Configure(fast)
+}
+
+// run starts a command without waiting for it.
+func run(e alarm.Event, command []string) error {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(),
+		"ALARM_NAME="+e.Rule.Name,
+		"ALARM_CHANNEL="+strconv.Itoa(e.Rule.Channel),
+		"ALARM_CONDITION="+e.Rule.Condition,
+		"ALARM_VALUE="+strconv.FormatFloat(e.Value, 'f', 4, 64),
+		"ALARM_TIME="+e.Time.Format(time.RFC3339Nano),
+	)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			log.Printf("alarm %s: %s: %v", e.Rule.Name, command[0], err)
+		}
+	}()
+	return nil
+}
+
+// close closes the event log.
+func (a *alarms) close() error {
+	if a.events == nil {
+		return nil
+	}
+	return a.events.close()
+}
+
+// eventLog appends alarm events to a CSV file kept across runs.
+type eventLog struct {
+	file   *os.File
+	writer *csv.Writer
+}
+
+// openEventLog opens path for appending, writing the header if it is new.
+func openEventLog(path string) (*eventLog, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	l := &eventLog{file: file, writer: csv.NewWriter(file)}
+	if info, err := file.Stat(); err == nil && info.Size() == 0 {
+		l.writer.Write([]string{"Timestamp", "Rule", "Channel", "Condition", "State", "Value"})
+	}
+	log.Printf("logging alarms to %s", path)
+	return l, nil
+}
+
+func (l *eventLog) write(e alarm.Event, channel string) error {
+	state := "cleared"
+	if e.Raised {
+		state = "raised"
+	}
+	l.writer.Write([]string{
+		e.Time.Format(time.RFC3339Nano),
+		e.Rule.Name,
+		channel,
+		e.Rule.Condition,
+		state,
+		strconv.FormatFloat(e.Value, 'f', 4, 64),
+	})
+	l.writer.Flush()
+	return l.writer.Error()
+}
+
+func (l *eventLog) close() error {
+	l.writer.Flush()
+	if err := l.writer.Error(); err != nil {
+		l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,132 @@
+// Package calibration converts raw ADC counts from the voltmeter into volts.
+// A reading is first scaled to a nominal voltage from the reference voltage
+// and ADC resolution, then corrected by an optional per-channel polynomial
+// measured against a trusted meter.
+package calibration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Calibration describes how counts on every channel become volts.
+type Calibration struct {
+	Reference  float64   `json:"reference"`  // voltage at full scale
+	Resolution int       `json:"resolution"` // bits per reading: 10, 12, or 16 for TinyGo's scaled ADC.Get()
+	Channels   []Channel `json:"channels"`   // corrections for individual channels
+}
+
+// Channel corrects the nominal voltage of one ADC channel.
+type Channel struct {
+	Channel    int       `json:"channel"`
+	Name       string    `json:"name"`
+	Polynomial []float64 `json:"polynomial"` // c0, c1, c2, ... giving c0 + c1·v + c2·v² + ...
+}
+
+// Default is an uncorrected 3.3 V board read through TinyGo, whose
+// ADC.Get() scales every reading to 16 bits.
+func Default() Calibration {
+	return Calibration{Reference: 3.3, Resolution: 16}
+}
+
+// Load reads a JSON calibration file. Fields missing from the file keep
+// their defaults.
+func Load(path string) (Calibration, error) {
+	c := Default()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return c, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Validate reports the first problem with the calibration.
+func (c Calibration) Validate() error {
+	if c.Reference <= 0 {
+		return fmt.Errorf("calibration: reference voltage must be positive, got %v", c.Reference)
+	}
+	if c.Resolution < 1 || c.Resolution > 16 {
+		return fmt.Errorf("calibration: resolution must be 1 to 16 bits, got %d", c.Resolution)
+	}
+	seen := map[int]bool{}
+	for _, ch := range c.Channels {
+		if seen[ch.Channel] {
+			return fmt.Errorf("calibration: channel %d listed twice", ch.Channel)
+		}
+		seen[ch.Channel] = true
+	}
+	return nil
+}
+
+// FullScale returns the largest count the ADC produces.
+func (c Calibration) FullScale() float64 {
+	return float64(uint32(1)<<uint(c.Resolution) - 1)
+}
+
+// Nominal returns the voltage a count represents before any correction.
+func (c Calibration) Nominal(counts uint16) float64 {
+	return float64(counts) / c.FullScale() * c.Reference
+}
+
+// Volts converts a count read on the given channel to volts.
+func (c Calibration) Volts(channel int, counts uint16) float64 {
+	v := c.Nominal(counts)
+	for _, ch := range c.Channels {
+		if ch.Channel == channel && len(ch.Polynomial) > 0 {
+			return evaluate(ch.Polynomial, v)
+		}
+	}
+	return v
+}
+
+// evaluate computes the polynomial with Horner's rule.
+func evaluate(coeffs []float64, v float64) float64 {
+	result := 0.0
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		result = result*v + coeffs[i]
+	}
+	return result
+}
+
+// ChannelName returns the configured name of a channel, or ADC<n>.
+func (c Calibration) ChannelName(channel int) string {
+	for _, ch := range c.Channels {
+		if ch.Channel == channel && ch.Name != "" {
+			return ch.Name
+		}
+	}
+	return "ADC" + strconv.Itoa(channel)
+}
+
+// Describe returns human readable lines recording the calibration, written
+// into the preamble of every recording.
+func (c Calibration) Describe() []string {
+	lines := []string{fmt.Sprintf("calibration: reference %g V, %d-bit counts", c.Reference, c.Resolution)}
+	for _, ch := range c.Channels {
+		if len(ch.Polynomial) == 0 {
+			continue
+		}
+		var terms []string
+		for i, k := range ch.Polynomial {
+			switch i {
+			case 0:
+				terms = append(terms, strconv.FormatFloat(k, 'g', -1, 64))
+			case 1:
+				terms = append(terms, strconv.FormatFloat(k, 'g', -1, 64)+"*v")
+			default:
+				terms = append(terms, strconv.FormatFloat(k, 'g', -1, 64)+"*v^"+strconv.Itoa(i))
+			}
+		}
+		lines = append(lines, fmt.Sprintf("calibration %s: %s", c.ChannelName(ch.Channel), strings.Join(terms, " + ")))
+	}
+	return lines
+}
@@ -2,4 +2,8 @@ module voltmeter
 
 go 1.21.1
 
-require github.com/stianeikeland/go-rpio v4.2.0+incompatible
+require (
+	github.com/jacobsa/go-serial v0.0.0-20180131005756-15cf729a72d4
+	github.com/stianeikeland/go-rpio v4.2.0+incompatible
+	golang.org/x/sys v0.19.0
+)
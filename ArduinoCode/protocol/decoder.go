@@ -0,0 +1,84 @@
+package protocol
+
+import (
+	"bufio"
+	"io"
+)
+
+// Stats counts what the decoder has seen on the stream.
+type Stats struct {
+	Frames   int // valid frames returned
+	Dropped  int // frames missing according to the sequence numbers
+	Corrupt  int // candidate frames rejected for a bad version or checksum
+	Skipped  int // bytes discarded while searching for a start marker
+	Restarts int // sequence numbers jumping backwards, as when the board resets
+}
+
+// Decoder reads frames from a byte stream, resynchronising on the next start
+// marker whenever it meets garbage or a corrupted frame.
+type Decoder struct {
+	r       *bufio.Reader
+	stats   Stats
+	lastSeq uint16
+	started bool
+}
+
+// NewDecoder returns a decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReaderSize(r, 2*FrameSize(MaxWords))}
+}
+
+// Stats returns the counters accumulated so far.
+func (d *Decoder) Stats() Stats {
+	return d.stats
+}
+
+// Next returns the next valid frame. Errors from the underlying reader are
+// returned as they are; bytes already buffered are kept, so Next may be
+// called again after a timeout.
+func (d *Decoder) Next() (Frame, error) {
+	for {
+		head, err := d.r.Peek(HeaderSize)
+		if err != nil && len(head) < 2 {
+			return Frame{}, err
+		}
+		if head[0] != Magic0 || head[1] != Magic1 {
+			d.r.Discard(1)
+			d.stats.Skipped++
+			continue
+		}
+		if err != nil {
+			return Frame{}, err
+		}
+
+		data, err := d.r.Peek(FrameSize(int(head[6])))
+		if err != nil {
+			return Frame{}, err
+		}
+		f, err := Parse(data)
+		if err != nil {
+			// Step past this start marker only, a real frame may begin inside the bad one
+			d.r.Discard(1)
+			d.stats.Corrupt++
+			continue
+		}
+		d.r.Discard(len(data))
+		d.count(f.Seq)
+		return f, nil
+	}
+}
+
+// count updates the frame and drop counters from a sequence number.
+func (d *Decoder) count(seq uint16) {
+	d.stats.Frames++
+	if d.started {
+		gap := seq - d.lastSeq - 1
+		if gap < 0x8000 {
+			d.stats.Dropped += int(gap)
+		} else {
+			d.stats.Restarts++
+		}
+	}
+	d.lastSeq = seq
+	d.started = true
+}
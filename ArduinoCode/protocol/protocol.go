@@ -0,0 +1,251 @@
+// Package protocol frames the binary messages exchanged between the
+// voltmeter firmware and the host reader. It is shared by both sides, so it
+// avoids fmt and allocations and builds with TinyGo.
+//
+// Every frame is laid out as
+//
+//	0xA5 0x5A | version | type | seq (2) | count | count × word (2) | crc (2)
+//
+// with multi-byte fields big endian. The CRC is CRC-16/CCITT-FALSE over
+// everything from the version byte to the last payload word. For sample
+// frames the count is the number of ADC channels and each word one reading.
+// Timed sample frames prefix the readings with the device tick count in
+// milliseconds as two words, high word first; channel sample frames add a
+// word with one bit per active ADC pin after the ticks. Info frames carry the
+// firmware version as major, minor and patch words. The host sends an empty
+// query frame as a handshake, which the firmware answers with an info frame
+// followed by a config frame.
+//
+// Configure and config frames hold the sampling settings as three words:
+// the channel mask, the sampling interval in milliseconds and the number of
+// readings averaged per sample. In a configure frame a zero word leaves
+// that setting unchanged.
+package protocol
+
+import "errors"
+
+// Start marker bytes opening every frame.
+const (
+	Magic0 = 0xA5
+	Magic1 = 0x5A
+)
+
+// Version is the protocol version written by this package. Frames of any
+// other version are rejected.
+const Version = 1
+
+// Frame types.
+const (
+	TypeSamples      byte = 1 // one reading per active ADC channel
+	TypeInfo         byte = 2 // firmware major, minor and patch version
+	TypeTimedSamples byte = 3 // device ticks (2 words) then one reading per channel
+	TypeQuery        byte = 4 // host handshake, answered with info and config frames
+	TypeConfigure    byte = 5 // host request to change the sampling settings
+	TypeConfig       byte = 6 // sampling settings in use, sent after every change
+	TypeChannels     byte = 7 // device ticks (2 words), channel mask, one reading per active channel
+)
+
+// MaxChannels is the number of ADC pins a channel mask can select.
+const MaxChannels = 16
+
+// Limits of the voltmeter firmware, which clamps any configure request
+// beyond them.
+const (
+	BoardChannels = 6  // ADC0 to ADC5, the pins the board can sample
+	MaxAveraging  = 64 // readings averaged into one sample
+)
+
+// Config is the firmware's sampling configuration.
+type Config struct {
+	Channels  uint16 // bit n selects ADC pin n
+	Interval  uint16 // milliseconds between samples
+	Averaging uint16 // readings averaged into each sample
+}
+
+// Words returns the payload of a configure or config frame.
+func (c Config) Words() [3]uint16 {
+	return [3]uint16{c.Channels, c.Interval, c.Averaging}
+}
+
+// ParseConfig reads a configure or config frame.
+func ParseConfig(f Frame) (Config, bool) {
+	if (f.Type != TypeConfigure && f.Type != TypeConfig) || len(f.Words) < 3 {
+		return Config{}, false
+	}
+	return Config{Channels: f.Words[0], Interval: f.Words[1], Averaging: f.Words[2]}, true
+}
+
+// Merge returns c with the non-zero settings of update applied.
+func (c Config) Merge(update Config) Config {
+	if update.Channels != 0 {
+		c.Channels = update.Channels
+	}
+	if update.Interval != 0 {
+		c.Interval = update.Interval
+	}
+	if update.Averaging != 0 {
+		c.Averaging = update.Averaging
+	}
+	return c
+}
+
+// ChannelList returns the ADC pins selected by a channel mask in order.
+func ChannelList(mask uint16) []int {
+	var channels []int
+	for n := 0; n < MaxChannels; n++ {
+		if mask&(1<<uint(n)) != 0 {
+			channels = append(channels, n)
+		}
+	}
+	return channels
+}
+
+// Sizes of the fixed parts of a frame.
+const (
+	HeaderSize  = 7 // magic, version, type, sequence number and count
+	TrailerSize = 2 // CRC
+	MaxWords    = 255
+)
+
+// Errors returned when a frame cannot be encoded or parsed.
+var (
+	ErrTooLong    = errors.New("protocol: too many words for one frame")
+	ErrShort      = errors.New("protocol: frame truncated")
+	ErrMagic      = errors.New("protocol: missing start marker")
+	ErrVersion    = errors.New("protocol: unsupported version")
+	ErrChecksum   = errors.New("protocol: checksum mismatch")
+	ErrUnexpected = errors.New("protocol: unexpected frame type")
+)
+
+// Frame is one decoded message.
+type Frame struct {
+	Version byte
+	Type    byte
+	Seq     uint16
+	Words   []uint16
+}
+
+// FrameSize returns the encoded size of a frame carrying n words.
+func FrameSize(n int) int {
+	return HeaderSize + 2*n + TrailerSize
+}
+
+// AppendFrame appends the encoding of a frame to buf and returns the
+// extended buffer. Passing a buffer with enough capacity avoids allocating,
+// which matters on the microcontroller.
+func AppendFrame(buf []byte, typ byte, seq uint16, words []uint16) ([]byte, error) {
+	if len(words) > MaxWords {
+		return buf, ErrTooLong
+	}
+	start := len(buf)
+	buf = append(buf, Magic0, Magic1, Version, typ, byte(seq>>8), byte(seq), byte(len(words)))
+	for _, w := range words {
+		buf = append(buf, byte(w>>8), byte(w))
+	}
+	crc := CRC16(buf[start+2:])
+	return append(buf, byte(crc>>8), byte(crc)), nil
+}
+
+// Parse decodes the frame at the start of data, which must hold exactly one
+// frame. The returned words are freshly allocated.
+func Parse(data []byte) (Frame, error) {
+	return parse(data, nil)
+}
+
+// parse decodes a frame, storing its words in words when it is large
+// enough and allocating otherwise.
+func parse(data []byte, words []uint16) (Frame, error) {
+	if len(data) < HeaderSize+TrailerSize {
+		return Frame{}, ErrShort
+	}
+	if data[0] != Magic0 || data[1] != Magic1 {
+		return Frame{}, ErrMagic
+	}
+	if data[2] != Version {
+		return Frame{}, ErrVersion
+	}
+	n := int(data[6])
+	if len(data) < FrameSize(n) {
+		return Frame{}, ErrShort
+	}
+	body := data[2 : HeaderSize+2*n]
+	got := uint16(data[HeaderSize+2*n])<<8 | uint16(data[HeaderSize+2*n+1])
+	if CRC16(body) != got {
+		return Frame{}, ErrChecksum
+	}
+
+	f := Frame{
+		Version: data[2],
+		Type:    data[3],
+		Seq:     uint16(data[4])<<8 | uint16(data[5]),
+	}
+	if n <= cap(words) {
+		f.Words = words[:n]
+	} else {
+		f.Words = make([]uint16, n)
+	}
+	for i := range f.Words {
+		f.Words[i] = uint16(data[HeaderSize+2*i])<<8 | uint16(data[HeaderSize+2*i+1])
+	}
+	return f, nil
+}
+
+// TickWords splits a device tick count into the two words leading a timed
+// sample frame.
+func TickWords(ticks uint32) (hi, lo uint16) {
+	return uint16(ticks >> 16), uint16(ticks)
+}
+
+// Samples are the readings carried by a sample frame.
+type Samples struct {
+	Readings []uint16
+	Channels uint16 // mask of the ADC pins the readings come from, in order
+	Ticks    uint32 // device milliseconds, valid when Timed
+	Timed    bool
+}
+
+// Samples returns the readings of a sample frame. ok is false for frames
+// that carry no samples. Frames without a channel mask come from the first
+// len(Readings) pins.
+func (f Frame) Samples() (s Samples, ok bool) {
+	switch f.Type {
+	case TypeSamples:
+		s.Readings = f.Words
+	case TypeTimedSamples:
+		if len(f.Words) < 2 {
+			return s, false
+		}
+		s.Readings, s.Timed = f.Words[2:], true
+	case TypeChannels:
+		if len(f.Words) < 3 {
+			return s, false
+		}
+		s.Readings, s.Channels, s.Timed = f.Words[3:], f.Words[2], true
+	default:
+		return s, false
+	}
+	if s.Timed {
+		s.Ticks = uint32(f.Words[0])<<16 | uint32(f.Words[1])
+	}
+	if f.Type != TypeChannels {
+		s.Channels = uint16(1)<<uint(len(s.Readings)) - 1
+	}
+	return s, true
+}
+
+// CRC16 returns the CRC-16/CCITT-FALSE checksum of data (polynomial 0x1021,
+// initial value 0xFFFF). It is computed bitwise to keep the firmware small.
+func CRC16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,51 @@
+package protocol
+
+// MaxCommandWords bounds the frames a Scanner accepts. Host commands are
+// short, so the firmware can keep its receive buffer small.
+const MaxCommandWords = 16
+
+// Scanner assembles frames one byte at a time into fixed buffers. The
+// firmware feeds it bytes as they arrive on the UART; the host uses Decoder.
+type Scanner struct {
+	buf   [HeaderSize + 2*MaxCommandWords + TrailerSize]byte
+	words [MaxCommandWords]uint16
+	n     int
+}
+
+// Feed adds one byte and returns a frame when b completes a valid one.
+// Bytes that cannot start a frame are discarded. The frame's words are only
+// valid until the next call.
+func (s *Scanner) Feed(b byte) (Frame, bool) {
+	switch {
+	case s.n == 0 && b != Magic0:
+		return Frame{}, false
+	case s.n == 1 && b != Magic1:
+		s.reset(b)
+		return Frame{}, false
+	}
+	s.buf[s.n] = b
+	s.n++
+	if s.n < HeaderSize {
+		return Frame{}, false
+	}
+	size := FrameSize(int(s.buf[6]))
+	if size > len(s.buf) {
+		s.n = 0
+		return Frame{}, false
+	}
+	if s.n < size {
+		return Frame{}, false
+	}
+	f, err := parse(s.buf[:size], s.words[:])
+	s.n = 0
+	return f, err == nil
+}
+
+// reset restarts the search, keeping b if it may open the next frame.
+func (s *Scanner) reset(b byte) {
+	s.n = 0
+	if b == Magic0 {
+		s.buf[0] = b
+		s.n = 1
+	}
+}
@@ -1,77 +1,124 @@
+//go:build !tinygo
+
 package main
 
 import (
-	"encoding/csv"
-	"io"
+	"flag"
 	"log"
 	"os"
-	"strconv"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
-	"github.com/jacobsa/go-serial/serial"
+	"voltmeter/acquire"
+	"voltmeter/alarm"
+	"voltmeter/protocol"
 )
 
 func main() {
-	// Set up options for the serial port
-	options := serial.OpenOptions{
-		PortName:        "/dev/ttyACM0",
-		BaudRate:        9600,
-		DataBits:        8,
-		StopBits:        1,
-		MinimumReadSize: 4,
+	settings := acquire.RegisterFlags(flag.CommandLine)
+	var opts segmentOptions
+	flag.StringVar(&opts.Dir, "dir", ".", "directory to write recordings to")
+	flag.StringVar(&opts.Prefix, "prefix", "voltage_readings", "start of each recording's file name, followed by its start time")
+	rotateMB := flag.Float64("rotate-size", 0, "start a new file once the CSV reaches this many megabytes (0 never)")
+	flag.DurationVar(&opts.MaxAge, "rotate-every", 0, "start a new file after this long (0 never)")
+	flag.BoolVar(&opts.Gzip, "gzip", false, "compress each file once it is finished")
+	flag.BoolVar(&opts.Binary, "binary", false, "also write each file in the compact binary format (.vlog)")
+	alarmsPath := flag.String("alarms", "", "JSON file of alarm rules to check readings against")
+	flag.Parse()
+	if settings.Replaying() {
+		log.Fatal("-replay: the logger only records the live board, replay recordings with LivePlot or WebDashboard")
 	}
-
-	// Open the serial port
-	port, err := serial.Open(options)
+	opts.MaxSize = int64(*rotateMB * 1e6)
+	config, cal, err := settings.Load()
 	if err != nil {
-		log.Fatalf("serial.Open: %v", err)
+		log.Fatal(err)
+	}
+	sampling, _ := config.Sampling()
+	var rules []alarm.Rule
+	if *alarmsPath != "" {
+		if rules, err = alarm.Load(*alarmsPath); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	// Create a new CSV file
-	file, err := os.Create("voltage_readings.csv")
-	if err != nil {
+	// Files are opened with the first reading, named after its time, and
+	// existing recordings are never overwritten
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
 		log.Fatal(err)
 	}
-	defer file.Close()
+	rec := newRecorder(&segmenter{opts: opts}, cal, metadata{
+		Port:    config.Port,
+		Baud:    config.Baud,
+		Started: time.Now(),
+	})
 
-	// Create a CSV writer
-	writer := csv.NewWriter(file)
+	// Frames are read on their own goroutine so a signal can end the
+	// recording while a read is blocked
+	conn := acquire.NewLink(config, sampling)
+	arrivals := conn.Stream()
 
-	// Write header to CSV file
-	header := []string{"Timestamp", "Voltage on ADC0 (V)", "Voltage on ADC1 (V)"}
-	err = writer.Write(header)
-	if err != nil {
-		log.Fatal(err)
-	}
-	writer.Flush()
+	// Alarm rules are checked on every reading, and once a second for
+	// channels that have gone quiet
+	watch := newAlarms(rules, conn, cal, filepath.Join(opts.Dir, opts.Prefix+"-events.csv"))
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
 
-	// Buffer to hold incoming data
-	buf := make([]byte, 2)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	dropped := 0
 
 	for {
-		// Read voltage on ADC0
-		_, err := port.Read(buf)
-		if err != nil {
-			if err != io.EOF {
+		var a acquire.Arrival
+		select {
+		case a = <-arrivals:
+		case now := <-tick.C:
+			watch.tick(now)
+			continue
+		case sig := <-stop:
+			log.Printf("%v: closing the recording", sig)
+			if err := rec.close(); err != nil {
 				log.Fatal(err)
 			}
-		}
-		voltageA0 := int(buf[0])<<8 | int(buf[1])
-
-		// Read voltage on ADC1
-		_, err = port.Read(buf)
-		if err != nil {
-			if err != io.EOF {
+			if err := watch.close(); err != nil {
 				log.Fatal(err)
 			}
+			summarise(rec, conn.Summary())
+			return
+		}
+		rec.meta.Port = a.Port
+		switch a.Frame.Type {
+		case protocol.TypeInfo:
+			rec.info(a.Frame)
+			continue
+		case protocol.TypeConfig:
+			rec.config(a.Frame)
+			watch.config(a.Frame)
+			continue
+		}
+
+		// Report frames lost since the last one
+		if stats := conn.Summary(); stats.Dropped != dropped {
+			dropped = stats.Dropped
+			log.Printf("dropped %d frames so far (%d corrupt, %d bytes skipped)", stats.Dropped, stats.Corrupt, stats.Skipped)
 		}
-		voltageA1 := int(buf[0])<<8 | int(buf[1])
 
 		// Write data to CSV file
-		record := []string{strconv.Itoa(voltageA0), strconv.Itoa(voltageA1)}
-		err = writer.Write(record)
-		if err != nil {
+		reading, ok := acquire.Read(a.Frame, a.Received, cal)
+		if !ok {
+			continue
+		}
+		if err := rec.sample(reading); err != nil {
 			log.Fatal(err)
 		}
-		writer.Flush()
+		watch.reading(reading)
 	}
 }
+
+// summarise logs what the recording captured and what went wrong.
+func summarise(rec *recorder, s acquire.Summary) {
+	log.Printf("recorded %d samples in %v across %d files", rec.samples, time.Since(rec.began).Round(time.Second), rec.segments.files)
+	log.Printf("%d frames, %d dropped, %d corrupt, %d bytes skipped, %d board restarts", s.Frames, s.Dropped, s.Corrupt, s.Skipped, s.Restarts)
+	log.Printf("%d reconnects, %d errors", s.Reconnects, s.Errors)
+}
@@ -0,0 +1,270 @@
+//go:build !tinygo
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"voltmeter/acquire"
+	"voltmeter/calibration"
+	"voltmeter/protocol"
+)
+
+// infoWait is how long samples are held back waiting for the firmware to
+// answer the link's query with its version, before the preamble is written
+// without it.
+const infoWait = 2 * time.Second
+
+// metadata describes a recording and is written as its preamble.
+type metadata struct {
+	Port     string
+	Baud     uint
+	Firmware string // empty until the device announces its version
+	Protocol byte
+	Sampling string // settings reported by the firmware, if any
+	Started  time.Time
+}
+
+// lines returns the preamble, one "key: value" entry per line.
+func (m metadata) lines() []string {
+	firmware := m.Firmware
+	if firmware == "" {
+		firmware = "unknown"
+	}
+	lines := []string{
+		"port: " + m.Port,
+		"baud: " + strconv.FormatUint(uint64(m.Baud), 10),
+		fmt.Sprintf("firmware: %s (protocol v%d)", firmware, m.Protocol),
+	}
+	if m.Sampling != "" {
+		lines = append(lines, "sampling: "+m.Sampling)
+	}
+	return append(lines, "started: "+m.Started.Format(time.RFC3339Nano))
+}
+
+// recorder writes sample frames as CSV rows, and optionally in the binary
+// format, across as many segments as the capture needs. Each segment has
+// its own preamble and header, held back until its first sample so the
+// header matches the channels the firmware actually sends. When the
+// firmware reports a different set of channels a new segment is started,
+// so every column it sends has a place in the header.
+type recorder struct {
+	segments *segmenter
+	out      io.Writer
+	writer   *csv.Writer
+	binary   *acquire.BinaryWriter // nil unless writing binary
+	cal      calibration.Calibration
+	meta     metadata // meta.Started is the start of the current segment
+	began    time.Time
+	columns  []int             // ADC pin of each voltage column, nil before the header
+	restart  bool              // the channels have changed, start a new segment
+	held     []acquire.Reading // samples waiting for the firmware version
+	waited   bool              // gave up waiting for the version
+	missing  uint16            // pins sampled but absent from the header, already reported
+	samples  int               // rows written over all segments
+}
+
+func newRecorder(segments *segmenter, cal calibration.Calibration, meta metadata) *recorder {
+	if meta.Protocol == 0 {
+		meta.Protocol = protocol.Version
+	}
+	return &recorder{segments: segments, cal: cal, meta: meta, began: meta.Started}
+}
+
+// info records the firmware version from an info frame.
+func (r *recorder) info(f protocol.Frame) {
+	if len(f.Words) < 3 {
+		return
+	}
+	r.meta.Firmware = fmt.Sprintf("%d.%d.%d", f.Words[0], f.Words[1], f.Words[2])
+	r.meta.Protocol = f.Version
+}
+
+// config records the sampling settings from a config frame.
+func (r *recorder) config(f protocol.Frame) {
+	c, ok := protocol.ParseConfig(f)
+	if !ok {
+		return
+	}
+	var names []string
+	for _, pin := range protocol.ChannelList(c.Channels) {
+		names = append(names, r.cal.ChannelName(pin))
+	}
+	sampling := fmt.Sprintf("%s every %d ms, averaging %d", strings.Join(names, ","), c.Interval, c.Averaging)
+	if r.columns != nil && sampling != r.meta.Sampling {
+		log.Printf("sampling changed to %s", sampling)
+	}
+	r.meta.Sampling = sampling
+	if r.columns != nil && !equalPins(r.columns, protocol.ChannelList(c.Channels)) {
+		r.restart = true
+	}
+}
+
+// equalPins reports whether two lists of pins are the same.
+func equalPins(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sample records a reading. Until the firmware's version is known, or
+// infoWait has passed, readings are held back so the preamble can name it:
+// a board that was already running only repeats its info frame every so
+// many samples.
+func (r *recorder) sample(reading acquire.Reading) error {
+	r.held = append(r.held, reading)
+	if r.meta.Firmware == "" && !r.waited {
+		if reading.Received.Sub(r.held[0].Received) < infoWait {
+			return nil
+		}
+		log.Printf("no firmware version after %v, recording it as unknown", infoWait)
+		r.waited = true
+	}
+	return r.release()
+}
+
+// release writes the held readings.
+func (r *recorder) release() error {
+	held := r.held
+	r.held = nil
+	for _, h := range held {
+		if err := r.write(h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write writes one row for a reading, first starting a new segment if the
+// current one is full or the channels have changed.
+func (r *recorder) write(reading acquire.Reading) error {
+	if r.restart || r.segments.due(reading.Received) {
+		if err := r.rotate(reading.Received); err != nil {
+			return err
+		}
+	}
+	if r.columns == nil {
+		if err := r.writeHeader(reading.Channels); err != nil {
+			return err
+		}
+	}
+
+	// Host time, seconds since the recording started and device ticks
+	record := []string{
+		reading.Received.Format(time.RFC3339Nano),
+		strconv.FormatFloat(reading.Received.Sub(r.meta.Started).Seconds(), 'f', 6, 64),
+		"",
+	}
+	if reading.Timed {
+		record[2] = strconv.FormatUint(uint64(reading.Ticks), 10)
+	}
+
+	// Readings go under their pin's column, left blank when it is not sampled
+	values := make([]string, len(r.columns))
+	for i, pin := range reading.Channels {
+		column := r.column(pin)
+		if column < 0 {
+			if r.missing&(1<<uint(pin)) == 0 {
+				log.Printf("%s is not in the header, its readings are not recorded", r.cal.ChannelName(pin))
+				r.missing |= 1 << uint(pin)
+			}
+			continue
+		}
+		values[column] = strconv.FormatFloat(reading.Volts[i], 'f', 4, 64)
+	}
+	record = append(record, values...)
+	if err := r.writer.Write(record); err != nil {
+		return err
+	}
+	if r.binary != nil {
+		if err := r.binary.Write(reading); err != nil {
+			return err
+		}
+	}
+	r.samples++
+	r.writer.Flush()
+	return r.writer.Error()
+}
+
+// rotate starts a new segment at now.
+func (r *recorder) rotate(now time.Time) error {
+	if err := r.flush(); err != nil {
+		return err
+	}
+	seg, err := r.segments.next(now)
+	if err != nil {
+		return err
+	}
+	r.out = &seg.size
+	r.writer = csv.NewWriter(r.out)
+	r.binary = nil
+	r.meta.Started = now
+	r.columns, r.restart, r.missing = nil, false, 0
+	return nil
+}
+
+// flush writes out any buffered rows.
+func (r *recorder) flush() error {
+	if r.writer == nil {
+		return nil
+	}
+	r.writer.Flush()
+	return r.writer.Error()
+}
+
+// close writes any held and buffered rows and finishes the last segment.
+func (r *recorder) close() error {
+	err := r.release()
+	if ferr := r.flush(); err == nil {
+		err = ferr
+	}
+	if cerr := r.segments.close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// column returns the voltage column of an ADC pin, or -1.
+func (r *recorder) column(pin int) int {
+	for i, p := range r.columns {
+		if p == pin {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeHeader writes the preamble, calibration and column names, and the
+// binary file's header when there is one.
+func (r *recorder) writeHeader(pins []int) error {
+	lines := append(r.meta.lines(), r.cal.Describe()...)
+	for _, line := range lines {
+		if _, err := fmt.Fprintf(r.out, "# %s\n", line); err != nil {
+			return err
+		}
+	}
+	if bin := r.segments.current.bin; bin != nil {
+		var err error
+		if r.binary, err = acquire.NewBinaryWriter(bin, lines); err != nil {
+			return err
+		}
+	}
+	header := []string{"Timestamp", "Elapsed (s)", "Device ticks (ms)"}
+	for _, pin := range pins {
+		header = append(header, "Voltage on "+r.cal.ChannelName(pin)+" (V)")
+	}
+	r.columns = pins
+	return r.writer.Write(header)
+}
@@ -0,0 +1,194 @@
+//go:build !tinygo
+
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// segmentOptions control how a long capture is split across files.
+type segmentOptions struct {
+	Dir     string
+	Prefix  string
+	MaxSize int64         // bytes of CSV before starting a new segment, 0 for no limit
+	MaxAge  time.Duration // time before starting a new segment, 0 for no limit
+	Gzip    bool          // compress each segment once it is finished
+	Binary  bool          // write a binary copy of each segment beside the CSV
+}
+
+// segment is the file, or pair of files, currently being written.
+type segment struct {
+	opened time.Time
+	csv    *os.File
+	bin    *os.File // nil unless writing binary
+	size   counter  // bytes of CSV written
+}
+
+// counter counts the bytes written through it.
+type counter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *counter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// segmenter opens segments named by their start time, never replacing an
+// existing file, and compresses finished ones in the background.
+type segmenter struct {
+	opts     segmentOptions
+	current  *segment
+	compress sync.WaitGroup
+	files    int // segments opened
+}
+
+// due reports whether the current segment is full or old enough that the
+// next reading at now should start a new one.
+func (s *segmenter) due(now time.Time) bool {
+	seg := s.current
+	if seg == nil {
+		return true
+	}
+	return (s.opts.MaxSize > 0 && seg.size.n >= s.opts.MaxSize) ||
+		(s.opts.MaxAge > 0 && now.Sub(seg.opened) >= s.opts.MaxAge)
+}
+
+// next finishes the current segment and opens one starting at now.
+func (s *segmenter) next(now time.Time) (*segment, error) {
+	if err := s.finish(); err != nil {
+		return nil, err
+	}
+	base, err := s.unusedBase(now)
+	if err != nil {
+		return nil, err
+	}
+	seg := &segment{opened: now}
+	if seg.csv, err = create(base + ".csv"); err != nil {
+		return nil, err
+	}
+	seg.size.w = seg.csv
+	if s.opts.Binary {
+		if seg.bin, err = create(base + ".vlog"); err != nil {
+			seg.csv.Close()
+			return nil, err
+		}
+	}
+	s.current = seg
+	s.files++
+	log.Printf("recording to %s", seg.csv.Name())
+	return seg, nil
+}
+
+// unusedBase returns the path, less its extension, of a segment starting at
+// now. A number is added when a segment already started in the same second.
+func (s *segmenter) unusedBase(now time.Time) (string, error) {
+	stamp := s.opts.Prefix + "-" + now.UTC().Format("20060102T150405Z")
+	for n := 1; n < 1000; n++ {
+		base := filepath.Join(s.opts.Dir, stamp)
+		if n > 1 {
+			base = fmt.Sprintf("%s-%d", base, n)
+		}
+		taken := false
+		for _, ext := range []string{".csv", ".csv.gz", ".vlog", ".vlog.gz"} {
+			if _, err := os.Stat(base + ext); !errors.Is(err, fs.ErrNotExist) {
+				taken = true
+			}
+		}
+		if !taken {
+			return base, nil
+		}
+	}
+	return "", fmt.Errorf("no free file name for %s in %s", stamp, s.opts.Dir)
+}
+
+// create opens a new file, failing rather than truncating one that exists.
+func create(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+}
+
+// finish closes the current segment and starts compressing it.
+func (s *segmenter) finish() error {
+	seg := s.current
+	if seg == nil {
+		return nil
+	}
+	s.current = nil
+	var paths []string
+	err := seg.csv.Close()
+	paths = append(paths, seg.csv.Name())
+	if seg.bin != nil {
+		if cerr := seg.bin.Close(); err == nil {
+			err = cerr
+		}
+		paths = append(paths, seg.bin.Name())
+	}
+	if err != nil {
+		return err
+	}
+	if s.opts.Gzip {
+		for _, path := range paths {
+			s.compress.Add(1)
+			go func(path string) {
+				defer s.compress.Done()
+				if err := gzipFile(path); err != nil {
+					log.Printf("compressing %s: %v", path, err)
+				}
+			}(path)
+		}
+	}
+	return nil
+}
+
+// close finishes the last segment and waits for compression to end.
+func (s *segmenter) close() error {
+	err := s.finish()
+	s.compress.Wait()
+	return err
+}
+
+// gzipFile replaces path with path.gz, keeping the original until the
+// compressed copy is safely written.
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	zw, err := gzip.NewWriterLevel(out, gzip.BestCompression)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	zw.Name = filepath.Base(path)
+	_, err = io.Copy(zw, in)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if serr := out.Sync(); err == nil {
+		err = serr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	return os.Remove(path)
+}
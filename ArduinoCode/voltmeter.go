@@ -1,38 +1,136 @@
+//go:build tinygo
+
 package main
 
 import (
 	"machine"
 	"time"
+
+	"voltmeter/protocol"
 )
 
-func main() {
-	// Configure ADC0 and ADC1 as analog inputs
-	adcA0 := machine.ADC{Pin: machine.ADC0}
-	adcA1 := machine.ADC{Pin: machine.ADC1}
+// Firmware version reported to the host as major, minor and patch
+var firmwareVersion = []uint16{1, 2, 0}
 
-	// Initialize the ADCs
-	adcA0.Configure(machine.ADCConfig{})
-	adcA1.Configure(machine.ADCConfig{})
+// An info frame is repeated every infoInterval samples so a reader started
+// after boot still learns the firmware version
+const infoInterval = 60
+
+// Analog pins the host can select, bit n of the channel mask is pins[n]
+var pins = []machine.Pin{machine.ADC0, machine.ADC1, machine.ADC2, machine.ADC3, machine.ADC4, machine.ADC5}
+
+// Limits on what the host may configure
+const (
+	maxAveraging = protocol.MaxAveraging
+	minInterval  = 1 // milliseconds
+)
+
+func main() {
+	// Configure every analog input, the mask picks which are read
+	adcs := make([]machine.ADC, len(pins))
+	for i, pin := range pins {
+		adcs[i] = machine.ADC{Pin: pin}
+		adcs[i].Configure(machine.ADCConfig{})
+	}
 
 	// Initialize the UART connection
 	uart := machine.UART0
 	uart.Configure(machine.UARTConfig{})
 
+	// Start with ADC0 and ADC1 once per second, as before configuration existed
+	config := protocol.Config{Channels: 0b11, Interval: 1000, Averaging: 1}
+
+	// Buffers are allocated once, the loop below must not allocate
+	words := make([]uint16, 3+len(pins))
+	frame := make([]byte, 0, protocol.FrameSize(len(words)))
+	var seq uint16
+	var samples int
+	var commands protocol.Scanner
+	boot := time.Now()
+	next := boot
+
+	// send frames words with the next sequence number
+	send := func(typ byte, words []uint16) {
+		frame, _ = protocol.AppendFrame(frame[:0], typ, seq, words)
+		uart.Write(frame)
+		seq++
+	}
+	announce := func() {
+		send(protocol.TypeInfo, firmwareVersion)
+		settings := config.Words()
+		send(protocol.TypeConfig, settings[:])
+	}
+
 	for {
-		// Read voltage on ADC0
-		voltageA0 := adcA0.Get() // Assuming 10-bit ADC
+		// Announce the firmware version and settings
+		if samples%infoInterval == 0 {
+			announce()
+		}
+		samples++
 
-		// Read voltage on ADC1
-		voltageA1 := adcA1.Get() // Assuming 10-bit ADC
+		// Stamp the sample with milliseconds since boot
+		words[0], words[1] = protocol.TickWords(uint32(time.Since(boot).Milliseconds()))
+		words[2] = config.Channels
 
-		// Write data to UART
-		uart.WriteByte(byte(voltageA0 >> 8))
-		uart.WriteByte(byte(voltageA0 & 0xFF))
-		uart.WriteByte('\n')
-		uart.WriteByte(byte(voltageA1 >> 8))
-		uart.WriteByte(byte(voltageA1 & 0xFF))
-		uart.WriteByte('\n')
+		// Read every active channel, averaging to reduce noise
+		n := 3
+		for i := range adcs {
+			if config.Channels&(1<<uint(i)) == 0 {
+				continue
+			}
+			var sum uint32
+			for k := uint16(0); k < config.Averaging; k++ {
+				sum += uint32(adcs[i].Get()) // TinyGo scales readings to 16 bits
+			}
+			words[n] = uint16(sum / uint32(config.Averaging))
+			n++
+		}
 
-		time.Sleep(time.Second)
+		// Write one framed sample to UART
+		send(protocol.TypeChannels, words[:n])
+
+		// Handle commands from the host
+		for uart.Buffered() > 0 {
+			b, _ := uart.ReadByte()
+			cmd, ok := commands.Feed(b)
+			if !ok {
+				continue
+			}
+			switch cmd.Type {
+			case protocol.TypeQuery:
+				announce()
+			case protocol.TypeConfigure:
+				update, _ := protocol.ParseConfig(cmd)
+				config = clamp(config.Merge(update))
+				settings := config.Words()
+				send(protocol.TypeConfig, settings[:])
+			}
+		}
+
+		// Wait for the next sample, skipping ahead if we fell behind
+		next = next.Add(time.Duration(config.Interval) * time.Millisecond)
+		if wait := time.Until(next); wait > 0 {
+			time.Sleep(wait)
+		} else {
+			next = time.Now()
+		}
+	}
+}
+
+// clamp keeps a configuration within what the board can do
+func clamp(c protocol.Config) protocol.Config {
+	c.Channels &= 1<<uint(len(pins)) - 1
+	if c.Channels == 0 {
+		c.Channels = 1
+	}
+	if c.Interval < minInterval {
+		c.Interval = minInterval
+	}
+	if c.Averaging < 1 {
+		c.Averaging = 1
+	}
+	if c.Averaging > maxAveraging {
+		c.Averaging = maxAveraging
 	}
+	return c
 }
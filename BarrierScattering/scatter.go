@@ -0,0 +1,319 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/camera"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/geometry"
+	"github.com/g3n/engine/gls"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/light"
+	"github.com/g3n/engine/material"
+	"github.com/g3n/engine/math32"
+	"github.com/g3n/engine/renderer"
+	"github.com/g3n/engine/util"
+	"github.com/g3n/engine/util/helper"
+	"github.com/g3n/engine/window"
+
+	"hackathon/axes"
+	"hackathon/axes/axes3d"
+	"hackathon/colormap"
+	"hackathon/controls"
+	"hackathon/hud"
+	"hackathon/observables"
+	"hackathon/scattering"
+	"hackathon/scenario"
+	"hackathon/systems"
+	"voltmeter/acquire"
+)
+
+// scn is the scenario describing this run
+var scn *scenario.Scenario
+
+// cmap is the colormap selected by the scenario
+var cmap colormap.Map = colormap.RedBlue
+
+func main() {
+	// Load the scenario describing this run, with the voltmeter flags for any inputs it binds
+	settings := acquire.RegisterFlags(flag.CommandLine)
+	scn = scenario.FromFlags(defaultScenario())
+	if scn.System.Kind != "scattering" {
+		log.Fatalf("this viewer shows scattering systems, scenario %q is a %s", scn.Name, scn.System.Kind)
+	}
+	sys, err := scn.Build()
+	if err != nil {
+		log.Fatal(err)
+	}
+	cmap = scn.ColorMap()
+
+	// Create application and scene
+	a := app.App()
+	scene := core.NewNode()
+	rater := util.NewFrameRater(60)
+
+	// Set the scene to be managed by the gui manager
+	gui.Manager().Set(scene)
+
+	// Create perspective camera
+	cam := camera.New(1)
+	cam.SetPosition(float32(scn.Camera.Position[0]), float32(scn.Camera.Position[1]), float32(scn.Camera.Position[2]))
+	scene.Add(cam)
+
+	// Set up orbit control for the camera
+	camera.NewOrbitControl(cam)
+
+	// Create the overlay of live observables and scattering probabilities
+	overlay := hud.New()
+	scene.Add(overlay)
+
+	// Set up callback to update viewport and camera aspect ratio when the window is resized
+	onResize := func(evname string, ev interface{}) {
+		// Get framebuffer size and update viewport accordingly
+		width, height := a.GetSize()
+		a.Gls().Viewport(0, 0, int32(width), int32(height))
+		// Update the camera's aspect ratio
+		cam.SetAspect(float32(width) / float32(height))
+		// Keep the overlay in the top right corner
+		overlay.Place(width)
+	}
+	a.Subscribe(window.OnWindowSize, onResize)
+	onResize("", nil)
+
+	// Axes span the sampled region with |ψ|² drawn up the screen, the points
+	// and the barrier get their own nodes so the control panel can replace them
+	graph := createGraph(scene, axisLayout(), scn.Axes.Ticks)
+	cloud := core.NewNode()
+	scene.Add(cloud)
+	// The overlay weighs the points by the density they were drawn from,
+	// worked out once when they are drawn
+	sampledAt := 0.0
+	points := scn.Sample(sys, sampledAt)
+	set := scn.SampleSet(sys, points, sampledAt)
+	mats, meshs := plotPoints(cloud, points)
+	scale := heightScale(sys, points)
+	walls := drawBarrier(scene, scn)
+
+	// Add the control panel for the barrier height, energy and width
+	panel := controls.New(scn)
+	scene.Add(panel)
+
+	// Voltmeter channels bound by the scenario steer it alongside the panel
+	knobs := controls.KnobsFromFlags(scn, settings)
+
+	// Create and add lights to the scene
+	scene.Add(light.NewAmbient(&math32.Color{1.0, 1.0, 1.0}, 0.8))
+	pointLight := light.NewPoint(&math32.Color{1, 1, 1}, 5.0)
+	pointLight.SetPosition(1, 0, 2)
+	scene.Add(pointLight)
+
+	// Create and add an axis helper to the scene
+	scene.Add(helper.NewAxes(0.5))
+
+	// Set background color to gray
+	a.Gls().ClearColor(0.5, 0.5, 0.5, 1.0)
+
+	t := 0.0
+	a.Run(func(rend *renderer.Renderer, deltaTime time.Duration) {
+		// Start measuring this frame
+		rater.Start()
+
+		// Clear the color, depth, and stencil buffers
+		a.Gls().Clear(gls.COLOR_BUFFER_BIT | gls.DEPTH_BUFFER_BIT | gls.STENCIL_BUFFER_BIT) // TODO maybe do inside renderer, and allow customization
+
+		// Render scene
+		err := rend.Render(scene, cam)
+		if err != nil {
+			panic(err)
+		}
+
+		// Send the packet again once both halves have left the barrier
+		t += deltaTime.Seconds() * scn.Solver.TimeScale
+		if sim, ok := sys.(*scattering.Simulation); ok && t > sim.Arrival()+sim.Duration() {
+			t = 0
+		}
+
+		// Apply edits made in the control panel or by the voltmeter since the last frame
+		change := panel.Poll() | knobs.Poll()
+		rebuilt := false
+		if change.Has(controls.Rebuild) {
+			// An energy the grid cannot resolve keeps the last system that worked
+			if next, err := scn.Build(); err != nil {
+				log.Print(err)
+			} else {
+				sys, t, rebuilt = next, 0, true
+				scene.Remove(walls)
+				walls.DisposeChildren(true)
+				walls = drawBarrier(scene, scn)
+			}
+		}
+		if change.Has(controls.Resample) {
+			scene.Remove(graph)
+			graph.DisposeChildren(true)
+			graph = createGraph(scene, axisLayout(), scn.Axes.Ticks)
+			scene.Remove(cloud)
+			cloud.DisposeChildren(true)
+			cloud = core.NewNode()
+			scene.Add(cloud)
+			sampledAt = t
+			points = scn.Sample(sys, sampledAt)
+			set = scn.SampleSet(sys, points, sampledAt)
+			mats, meshs = plotPoints(cloud, points)
+		}
+		if rebuilt {
+			scale = heightScale(sys, points)
+		}
+		if change.Has(controls.Recolor) {
+			cmap = scn.ColorMap()
+		}
+
+		for i := 0; i < len(points); i++ {
+			val := systems.Quantity(sys.Evaluate(points[i][0], points[i][1], points[i][2], t), scn.Display)
+			mats[i].SetColor(GenerateColorOnGradient(val * scale / maxHeight()))
+			meshs[i].SetPosition(float32(points[i][0]), float32(val*scale), float32(points[i][1]))
+		}
+
+		// Refresh the overlay twice a second
+		if fps, _, ok := rater.FPS(time.Second / 2); ok {
+			overlay.SetNotes(notes(sys)...)
+			overlay.Update(t, observables.Measure(sys, set, t, scn.Hamiltonian()), fps)
+		}
+
+		// Update GUI timers
+		gui.Manager().TimerManager.ProcessTimers()
+
+		// Control and update FPS
+		rater.Wait()
+	})
+}
+
+// notes returns the overlay lines giving how much is transmitted and
+// reflected: measured so far for a packet alongside the plane wave values
+// at its mean energy, or the plane wave values alone
+func notes(sys systems.System) []string {
+	hbar, mass := scn.System.Constants()
+	transmitted, reflected, err := scattering.Coefficients(scn.System.Barrier.Profile(), scn.System.Incident.Energy, hbar, mass)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	lines := []string{fmt.Sprintf("T plane %.4f", transmitted), fmt.Sprintf("R plane %.4f", reflected)}
+	if sim, ok := sys.(*scattering.Simulation); ok {
+		transmitted, reflected := sim.Measure()
+		lines = append(lines, fmt.Sprintf("T so far %.4f", transmitted), fmt.Sprintf("R so far %.4f", reflected))
+	}
+	return lines
+}
+
+// defaultScenario is the packet meeting a barrier this viewer shows when no
+// scenario file is given
+func defaultScenario() *scenario.Scenario {
+	return &scenario.Scenario{
+		Name: "wavepacket scattering",
+		System: scenario.System{
+			Kind:     "scattering",
+			Width:    []float64{80, 40}, // region the packet moves through
+			Barrier:  scenario.Barrier{Shape: "barrier", Height: 1, Thickness: 1.5},
+			Incident: scenario.Incident{Kind: "packet", Energy: 1.2, Spread: 3},
+		},
+		Solver:   scenario.Solver{TimeScale: 5},
+		Sampling: scenario.Sampling{Strategy: "random", Points: 15000},
+		Camera:   scenario.Camera{Position: []float64{0, 40, 60}},
+	}
+}
+
+// maxHeight is how far up the screen the highest point is drawn
+func maxHeight() float64 {
+	return scn.System.Width[0] / 4
+}
+
+// heightScale maps the quantity shown onto the screen so the packet's peak
+// as it starts out reaches maxHeight. It is only called on a system just
+// built, as evaluating a packet at t = 0 sends it back to its start
+func heightScale(sys systems.System, points [][]float64) float64 {
+	peak := 0.0
+	for _, p := range points {
+		peak = math.Max(peak, math.Abs(systems.Quantity(sys.Evaluate(p[0], p[1], p[2], 0), scn.Display)))
+	}
+	if peak == 0 {
+		return 1
+	}
+	return maxHeight() / peak
+}
+
+// drawBarrier draws each region where the potential differs from the left
+// hand side as a translucent slab, its height proportional to the potential
+func drawBarrier(scene *core.Node, scn *scenario.Scenario) *core.Node {
+	node := core.NewNode()
+	profile := scn.System.Barrier.Profile()
+	top := math.Max(math.Abs(profile.Max()), math.Abs(profile.Values[0]))
+	min, max := scn.Extent()
+	depth := math.Max(max[1]-min[1], 0.5)
+	for i, v := range profile.Values {
+		if v == profile.Values[0] || top == 0 {
+			continue
+		}
+		left, right := min[0], max[0]
+		if i > 0 {
+			left = profile.Edges[i-1]
+		}
+		if i < len(profile.Edges) {
+			right = profile.Edges[i]
+		}
+		height := v / top * maxHeight() / 2
+		geom := geometry.NewBox(float32(right-left), float32(math.Abs(height)), float32(depth))
+		mat := material.NewStandard(&math32.Color{R: 0.9, G: 0.9, B: 0.9})
+		mat.SetOpacity(0.35)
+		mat.SetTransparent(true)
+		mesh := graphic.NewMesh(geom, mat)
+		mesh.SetPosition(float32((left+right)/2), float32(height/2), float32((min[1]+max[1])/2))
+		node.Add(mesh)
+	}
+	scene.Add(node)
+	return node
+}
+
+// axisLayout returns the scenario's axes with the wavefunction drawn up the screen
+func axisLayout() [3]axes.Axis {
+	layout := scn.AxisLayout()
+	if scn.Axes.Z.Label == "" {
+		layout[2].Label, layout[2].Unit = "|ψ|²", ""
+		layout[2].Range = axes.Range{Min: 0, Max: maxHeight()}
+	}
+	return layout
+}
+
+// createGraph draws labelled axes for the physical x, y and z ranges, with z up the screen
+func createGraph(scene *core.Node, layout [3]axes.Axis, ticks int) *core.Node {
+	cfg := axes3d.DefaultConfig([3]axes.Axis{layout[0], layout[2], layout[1]})
+	cfg.Ticks = ticks
+	graph := axes3d.New(cfg)
+	scene.Add(graph)
+	return graph
+}
+
+func plotPoints(scene *core.Node, points [][]float64) ([]*material.Standard, []*graphic.Mesh) {
+	var mats []*material.Standard
+	var meshs []*graphic.Mesh
+	for i := 0; i < len(points); i++ {
+		geom := geometry.NewCube(0.3)
+		mat := material.NewStandard(math32.NewColor("DarkBlue"))
+		mats = append(mats, mat)
+		mesh := graphic.NewMesh(geom, mat)
+		meshs = append(meshs, mesh)
+		mesh.SetPosition(float32(points[i][0]), 0, float32(points[i][1]))
+		scene.Add(mesh)
+	}
+	return mats, meshs
+}
+
+// GenerateColorOnGradient generates a color on the scenario's colormap based on the input value (0 to 1)
+func GenerateColorOnGradient(value float64) *math32.Color {
+	red, green, blue := cmap(value)
+	return &math32.Color{R: red, G: green, B: blue}
+}
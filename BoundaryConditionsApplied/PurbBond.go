@@ -1,9 +1,11 @@
 package main
 
 import (
+	"fmt"
+	"image"
+	"log"
 	"math"
 	"math/cmplx"
-	"math/rand"
 	"time"
 
 	"github.com/g3n/engine/app"
@@ -17,13 +19,52 @@ import (
 	"github.com/g3n/engine/material"
 	"github.com/g3n/engine/math32"
 	"github.com/g3n/engine/renderer"
+	"github.com/g3n/engine/texture"
 	"github.com/g3n/engine/util"
 	"github.com/g3n/engine/util/helper"
 	"github.com/g3n/engine/window"
 	"gonum.org/v1/gonum/stat"
+
+	"hackathon/axes"
+	"hackathon/axes/axes3d"
+	"hackathon/colormap"
+	"hackathon/scenario"
+	"hackathon/stripchart"
+	"hackathon/systems"
 )
 
+// scn is the scenario describing this run
+var scn *scenario.Scenario
+
+// cmap is the colormap selected by the scenario
+var cmap colormap.Map = colormap.RedBlue
+
+// well is the double well a scenario of that kind describes, nil for the
+// well worked out in calculateWaveFunction
+var well *systems.DoubleWell
+
+// plotWidth and plotHeight are the size of the left well probability plot
+const plotWidth, plotHeight = 420, 220
+
 func main() {
+	// Load the scenario describing this run
+	scn = scenario.FromFlags(defaultScenario())
+	if len(scn.System.Width) != 2 {
+		log.Fatalf("this viewer shows 2D systems, scenario %q has %d dimensions", scn.Name, len(scn.System.Width))
+	}
+	cmap = scn.ColorMap()
+	if scn.System.Drive.Kind != "none" {
+		log.Fatalf("this viewer does not drive systems, run scenario %q in PurbatedSystem", scn.Name)
+	}
+	if scn.System.Kind == "double-well" {
+		sys, err := scn.Build()
+		if err != nil {
+			log.Fatal(err)
+		}
+		well = sys.(*systems.DoubleWell)
+		log.Printf("tunnelling period %.6g", well.Period())
+	}
+
 	// Create application and scene
 	a := app.App()
 	scene := core.NewNode()
@@ -34,12 +75,21 @@ func main() {
 
 	// Create perspective camera
 	cam := camera.New(1)
-	cam.SetPosition(0, 0, 3)
+	cam.SetPosition(float32(scn.Camera.Position[0]), float32(scn.Camera.Position[1]), float32(scn.Camera.Position[2]))
 	scene.Add(cam)
 
 	// Set up orbit control for the camera
 	camera.NewOrbitControl(cam)
 
+	// A double well plots the probability of the left well against time in the corner
+	var plot *gui.Image
+	var plotTex *texture.Texture2D
+	if well != nil {
+		plotTex = texture.NewTexture2DFromRGBA(tunnellingPlot(0))
+		plot = gui.NewImageFromTex(plotTex)
+		scene.Add(plot)
+	}
+
 	// Set up callback to update viewport and camera aspect ratio when the window is resized
 	onResize := func(evname string, ev interface{}) {
 		// Get framebuffer size and update viewport accordingly
@@ -47,21 +97,23 @@ func main() {
 		a.Gls().Viewport(0, 0, int32(width), int32(height))
 		// Update the camera's aspect ratio
 		cam.SetAspect(float32(width) / float32(height))
+		// Keep the plot in the bottom left corner
+		if plot != nil {
+			plot.SetPosition(10, float32(height-plotHeight-10))
+		}
 	}
 	a.Subscribe(window.OnWindowSize, onResize)
 	onResize("", nil)
 
-	// axis length
-	//TODO function call to set maxs
-	xLength := float64(12)
-	zLength := float64(1)
-	yLength := float64(12)
-
-	//if max is 10 min is -5
-
-	createGraph(scene, xLength, zLength, yLength)
-	points := generateRandomCoords(10000, 0, xLength, 0, yLength, 0, 0)
+	// Axes span the sampled region, with the wavefunction drawn up the screen
+	layout := scn.AxisLayout()
+	if scn.Axes.Z.Label == "" {
+		layout[2].Label, layout[2].Unit = "ψ", ""
+	}
+	createGraph(scene, layout, scn.Axes.Ticks)
+	points := scn.Sample(systems.WaveFunction(evaluate), 0)
 	mats, meshs := plotPoints(scene, points)
+	scale := heightScale(points)
 
 	// Create and add lights to the scene
 	scene.Add(light.NewAmbient(&math32.Color{1.0, 1.0, 1.0}, 0.8))
@@ -89,11 +141,26 @@ func main() {
 			panic(err)
 		}
 
+		if well != nil {
+			// Every point of a double well shares one clock so the two wells stay in step
+			t = t + scn.Solver.TimeStep
+		}
 		for i := 0; i < len(points); i++ {
-			t = t + 1.0
+			if well == nil {
+				t = t + scn.Solver.TimeStep
+			}
 			val := calculateWaveFunction(points[i][0], points[i][1], t)
-			mats[i].SetColor(GenerateColorOnGradient((0 + real(val)*(1))))
-			meshs[i].SetPosition(float32(points[i][0]), float32(real(val)), float32(points[i][1]))
+			height := real(val)
+			if well != nil {
+				height = systems.Quantity(val, scn.Display) * scale
+			}
+			mats[i].SetColor(GenerateColorOnGradient((0 + height*(1))))
+			meshs[i].SetPosition(float32(points[i][0]), float32(height), float32(points[i][1]))
+		}
+
+		// Redraw the plot twice a second
+		if _, _, ok := rater.FPS(time.Second / 2); ok && plot != nil {
+			plotTex.SetFromRGBA(tunnellingPlot(t))
 		}
 
 		// Update GUI timers
@@ -105,17 +172,21 @@ func main() {
 }
 
 func calculateWaveFunction(x, y, t float64) complex128 {
+	if well != nil {
+		return well.Evaluate(x, y, 0, t)
+	}
+
 	// Constants for the infinite square well problem
-	a := 10.0             // width of the well
-	ny, nz := 1.0, 1.0    // quantum numbers for each dimension
-	hbar := 1.0545718e-34 // reduced Planck's constant
-	m := 9.10938356e-31   // mass of the particle
+	a := scn.System.Width[0]      // width of the well
+	n := scn.System.States()[0].N // quantum numbers for each dimension
+	ny, nz := float64(n[1]), float64(n[0])
+	hbar, m := scn.System.Constants() // reduced Planck's constant and mass of the particle
 
 	// Calculate the real part of the wave function for each dimension
 	realPartX := x
 	realPartY := y
 	realPartZ := 0.0
-	if x <= 10 && y <= 10 {
+	if x <= a && y <= scn.System.Width[1] {
 		realPartZ = 2 / a * math.Sin(nz*math.Pi*x/a) * math.Sin(ny*math.Pi*y/a) * math.Cos(t*math.Pi*math.Pi*hbar/(m*a*a))
 	}
 	// Calculate the imaginary part of the wave function
@@ -127,25 +198,64 @@ func calculateWaveFunction(x, y, t float64) complex128 {
 	return waveFunction
 }
 
-func createGraph(scene *core.Node, xLength, zLength, yLength float64) {
-	// x axis
-	geomX := geometry.NewBox(float32(xLength), 0.05, 0.05)
-	matX := material.NewStandard(math32.NewColor("DarkBlue"))
-	meshX := graphic.NewMesh(geomX, matX)
-	meshX.SetPosition(float32(xLength/2), 0, 0)
-	scene.Add(meshX)
-	// z axis
-	geomZ := geometry.NewBox(0.05, float32(zLength), 0.05)
-	matZ := material.NewStandard(math32.NewColor("DarkBlue"))
-	meshZ := graphic.NewMesh(geomZ, matZ)
-	meshZ.SetPosition(0, float32(zLength/2), 0)
-	scene.Add(meshZ)
-	// y axis
-	geomY := geometry.NewBox(0.05, 0.05, float32(yLength))
-	matY := material.NewStandard(math32.NewColor("DarkBlue"))
-	meshY := graphic.NewMesh(geomY, matY)
-	meshY.SetPosition(0, 0, float32(yLength/2))
-	scene.Add(meshY)
+// defaultScenario is the bounded 2D well this viewer shows when no scenario
+// file is given
+func defaultScenario() *scenario.Scenario {
+	return &scenario.Scenario{
+		Name: "bounded square well",
+		System: scenario.System{
+			Kind:    "infinite-well",
+			Width:   []float64{10, 10}, // width of the well
+			Quantum: []int{1, 1},       // quantum numbers for each dimension
+			Units:   "si",
+		},
+		Solver:   scenario.Solver{TimeStep: 1.0},
+		Sampling: scenario.Sampling{Strategy: "random", Points: 10000, Extent: []float64{12, 12}},
+	}
+}
+
+// heightScale maps a double well's quantity onto the screen so its peak as
+// it starts out is drawn at height 1, where the colormap tops out
+func heightScale(points [][]float64) float64 {
+	if well == nil {
+		return 1
+	}
+	peak := 0.0
+	for _, p := range points {
+		peak = math.Max(peak, math.Abs(systems.Quantity(well.Evaluate(p[0], p[1], 0, 0), scn.Display)))
+	}
+	if peak == 0 {
+		return 1
+	}
+	return 1 / peak
+}
+
+// tunnellingPlot draws the probability of finding the particle in the left
+// well over the two tunnelling periods up to t, or the first two, with a
+// cross at t
+func tunnellingPlot(t float64) *image.RGBA {
+	period := well.Period()
+	start := math.Max(0, t-2*period)
+	curve := stripchart.Trace{Label: "left well", Color: 1}
+	for i := 0; i <= 200; i++ {
+		at := start + 2*period*float64(i)/200
+		curve.X, curve.Y = append(curve.X, at), append(curve.Y, well.Left(at))
+	}
+	now := stripchart.Trace{Label: "now", Color: 0, Marks: true, X: []float64{t}, Y: []float64{well.Left(t)}}
+	pane := stripchart.Pane{Title: fmt.Sprintf("period %.4g", period), XLabel: "t", Traces: []stripchart.Trace{curve, now}}
+	return stripchart.Plot([]stripchart.Pane{pane}, stripchart.Options{Width: plotWidth, Height: plotHeight, Ticks: 3})
+}
+
+// evaluate adapts calculateWaveFunction to the systems contract used for sampling
+func evaluate(x, y, z, t float64) complex128 {
+	return calculateWaveFunction(x, y, t)
+}
+
+// createGraph draws labelled axes for the physical x, y and z ranges, with z up the screen
+func createGraph(scene *core.Node, layout [3]axes.Axis, ticks int) {
+	cfg := axes3d.DefaultConfig([3]axes.Axis{layout[0], layout[2], layout[1]})
+	cfg.Ticks = ticks
+	scene.Add(axes3d.New(cfg))
 }
 
 func plotPoints(scene *core.Node, points [][]float64) ([]*material.Standard, []*graphic.Mesh) {
@@ -164,36 +274,9 @@ func plotPoints(scene *core.Node, points [][]float64) ([]*material.Standard, []*
 	return mats, meshs
 }
 
-// generateRandomCoords generates random arrays of float64 within specified bounds
-func generateRandomCoords(numCoords int, minX, maxX, minY, maxY, minZ, maxZ float64) [][]float64 {
-	// Create the 2D array to store the random arrays
-	result := make([][]float64, numCoords)
-	r := rand.New(rand.NewSource(38))
-
-	// Generate random values for each array
-	for i := 0; i < numCoords; i++ {
-		result[i] = make([]float64, 3)
-		result[i][0] = minX + r.Float64()*(maxX-minX) // Random value for x
-		result[i][1] = minY + r.Float64()*(maxY-minY) // Random value for y
-		result[i][2] = minZ + r.Float64()*(maxZ-minZ) // Random value for z
-	}
-	return result
-}
-
-// GenerateColorOnGradient generates a color on a gradient from red to blue based on the input value (0 to 1)
+// GenerateColorOnGradient generates a color on the scenario's colormap based on the input value (0 to 1)
 func GenerateColorOnGradient(value float64) *math32.Color {
-	// Ensure the value is within the range of 0 to 1
-	if value < 0 {
-		value = 0
-	} else if value > 1 {
-		value = 1
-	}
-
-	// Interpolate between red and blue based on the input value
-	red := 1 - float32(value)
-	blue := float32(value)
-	green := float32(0)
-
+	red, green, blue := cmap(value)
 	return &math32.Color{R: red, G: green, B: blue}
 }
 func NormalizeVals(vals []float64) []float64 {
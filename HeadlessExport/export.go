@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"math"
+	"os"
+	"strings"
+
+	"hackathon/colormap"
+	"hackathon/scenario"
+	"hackathon/stripchart"
+	"hackathon/systems"
+)
+
+// HeadlessExport evaluates a scenario without opening a window and writes
+// every output it lists.
+func main() {
+	path := flag.String("scenario", "", "YAML scenario file describing the run")
+	flag.Parse()
+	if *path == "" {
+		log.Fatal("usage: HeadlessExport -scenario file.yaml")
+	}
+
+	scn, err := scenario.Load(*path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sys, err := scn.Build()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(scn.Outputs) == 0 {
+		log.Fatalf("%s lists no outputs", *path)
+	}
+
+	for _, out := range scn.Outputs {
+		switch out.Kind {
+		case "csv":
+			err = writeCSV(scn, sys, out)
+		case "png":
+			err = writePNG(scn, sys, out)
+		case "tunnelling":
+			err = writeTunnelling(sys.(*systems.DoubleWell), out)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("wrote %s", out.Path)
+	}
+}
+
+// writeCSV writes x,y,z,value rows for the scenario's sample points, in the
+// same layout as wave_function_results.csv.
+func writeCSV(scn *scenario.Scenario, sys systems.System, out scenario.Output) error {
+	file, err := os.Create(out.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	for _, p := range scn.Sample(sys, out.Time) {
+		val := systems.Quantity(sys.Evaluate(p[0], p[1], p[2], out.Time), out.Quantity)
+		fmt.Fprintf(w, "%f,%f,%f,%f\n", p[0], p[1], p[2], val)
+	}
+	return w.Flush()
+}
+
+// writePNG renders a slice through the sampled box as a heat map.
+func writePNG(scn *scenario.Scenario, sys systems.System, out scenario.Output) error {
+	min, max := scn.Extent()
+	u, v, w := planeAxes(out.Plane)
+
+	vals := make([]float64, out.Width*out.Height)
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for j := 0; j < out.Height; j++ {
+		for i := 0; i < out.Width; i++ {
+			var pos [3]float64
+			pos[u] = min[u] + (float64(i)+0.5)/float64(out.Width)*(max[u]-min[u])
+			// Image rows run top to bottom, the axis runs bottom to top
+			pos[v] = max[v] - (float64(j)+0.5)/float64(out.Height)*(max[v]-min[v])
+			pos[w] = out.Slice
+			val := systems.Quantity(sys.Evaluate(pos[0], pos[1], pos[2], out.Time), out.Quantity)
+			vals[j*out.Width+i] = val
+			lo = math.Min(lo, val)
+			hi = math.Max(hi, val)
+		}
+	}
+
+	cmap := scn.ColorMap()
+	img := image.NewRGBA(image.Rect(0, 0, out.Width, out.Height))
+	for j := 0; j < out.Height; j++ {
+		for i := 0; i < out.Width; i++ {
+			img.Set(i, j, toRGBA(cmap, normalise(vals[j*out.Width+i], lo, hi)))
+		}
+	}
+
+	file, err := os.Create(out.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return png.Encode(file, img)
+}
+
+// writeTunnelling writes the probability of finding a double well's
+// particle in its left well against time, as rows of t,probability when
+// the path ends in .csv and as a plot otherwise.
+func writeTunnelling(well *systems.DoubleWell, out scenario.Output) error {
+	end := out.Time
+	if end == 0 {
+		end = 3 * well.Period()
+	}
+	const samples = 600
+	trace := stripchart.Trace{Label: "left well", Color: 1}
+	for i := 0; i <= samples; i++ {
+		t := end * float64(i) / samples
+		trace.X, trace.Y = append(trace.X, t), append(trace.Y, well.Left(t))
+	}
+
+	file, err := os.Create(out.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if strings.HasSuffix(out.Path, ".csv") {
+		w := bufio.NewWriter(file)
+		for i := range trace.X {
+			fmt.Fprintf(w, "%g,%f\n", trace.X[i], trace.Y[i])
+		}
+		return w.Flush()
+	}
+	pane := stripchart.Pane{Title: fmt.Sprintf("left well probability, tunnelling period %.4g", well.Period()), XLabel: "t", Traces: []stripchart.Trace{trace}}
+	return png.Encode(file, stripchart.Plot([]stripchart.Pane{pane}, stripchart.Options{Width: out.Width, Height: out.Height}))
+}
+
+// planeAxes returns the horizontal, vertical and normal axes of a plane.
+func planeAxes(plane string) (u, v, w int) {
+	switch plane {
+	case "xz":
+		return 0, 2, 1
+	case "yz":
+		return 1, 2, 0
+	}
+	return 0, 1, 2
+}
+
+func normalise(val, lo, hi float64) float64 {
+	if hi == lo {
+		return 0
+	}
+	return (val - lo) / (hi - lo)
+}
+
+func toRGBA(cmap colormap.Map, value float64) color.RGBA {
+	r, g, b := cmap(value)
+	return color.RGBA{R: uint8(r * 255), G: uint8(g * 255), B: uint8(b * 255), A: 255}
+}
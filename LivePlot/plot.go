@@ -0,0 +1,181 @@
+// LivePlot shows the voltmeter's readings as scrolling line graphs, one pane
+// per channel with min/max/mean readouts. It draws into a g3n window, or with
+// -png writes the same chart to a snapshot file at a fixed interval so a run
+// can be watched over ssh or from a browser. With -replay it plays back a
+// recording instead of reading the board.
+package main
+
+import (
+	"flag"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/camera"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gls"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/renderer"
+	"github.com/g3n/engine/texture"
+	"github.com/g3n/engine/util"
+	"github.com/g3n/engine/window"
+
+	"hackathon/stripchart"
+	"voltmeter/acquire"
+)
+
+func main() {
+	settings := acquire.RegisterFlags(flag.CommandLine)
+	span := flag.Duration("span", 30*time.Second, "length of history shown")
+	snapshot := flag.String("png", "", "write snapshots to this PNG file instead of opening a window")
+	every := flag.Duration("every", 5*time.Second, "time between snapshots with -png")
+	width := flag.Int("width", 1200, "snapshot width in pixels")
+	height := flag.Int("height", 700, "snapshot height in pixels")
+	flag.Parse()
+
+	config, cal, err := settings.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	readings, err := settings.Open(config, cal)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	win := stripchart.NewWindow(*span)
+	opts := stripchart.Options{Width: *width, Height: *height, Name: cal.ChannelName}
+	if *snapshot != "" {
+		headless(readings, win, opts, *snapshot, *every)
+		return
+	}
+	view(readings, win, opts)
+}
+
+// headless writes a snapshot every interval until interrupted or the
+// replay ends, then a final one.
+func headless(readings <-chan acquire.Reading, win *stripchart.Window, opts stripchart.Options, path string, every time.Duration) {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case r, ok := <-readings:
+			if !ok {
+				if err := writePNG(path, stripchart.Render(win, opts)); err != nil {
+					log.Fatal(err)
+				}
+				return
+			}
+			win.Add(r)
+		case <-ticker.C:
+			if err := writePNG(path, stripchart.Render(win, opts)); err != nil {
+				log.Fatal(err)
+			}
+		case <-stop:
+			if err := writePNG(path, stripchart.Render(win, opts)); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+}
+
+// writePNG replaces path atomically, so a viewer never sees half a file.
+func writePNG(path string, img image.Image) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".liveplot-*.png")
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(tmp, img); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// view draws the chart as a full window image, redrawn whenever readings
+// arrive or the window changes size.
+func view(readings <-chan acquire.Reading, win *stripchart.Window, opts stripchart.Options) {
+	// Create application and scene
+	a := app.App()
+	scene := core.NewNode()
+	rater := util.NewFrameRater(30)
+
+	// Set the scene to be managed by the gui manager
+	gui.Manager().Set(scene)
+
+	// The chart is drawn in screen space, the camera only satisfies the renderer
+	cam := camera.New(1)
+	scene.Add(cam)
+
+	// The chart lives in one texture the size of the window
+	tex := texture.NewTexture2DFromRGBA(stripchart.Render(win, opts))
+	chart := gui.NewImageFromTex(tex)
+	scene.Add(chart)
+
+	dirty := true
+	onResize := func(evname string, ev interface{}) {
+		width, height := a.GetSize()
+		a.Gls().Viewport(0, 0, int32(width), int32(height))
+		cam.SetAspect(float32(width) / float32(height))
+		opts.Width, opts.Height = width, height
+		chart.SetSize(float32(width), float32(height))
+		dirty = true
+	}
+	a.Subscribe(window.OnWindowSize, onResize)
+	onResize("", nil)
+
+	a.Gls().ClearColor(0, 0, 0, 1.0)
+
+	a.Run(func(rend *renderer.Renderer, deltaTime time.Duration) {
+		// Start measuring this frame
+		rater.Start()
+
+		// Take every reading that arrived since the last frame
+		for drained := false; !drained; {
+			select {
+			case r, ok := <-readings:
+				if !ok {
+					// The replay has ended, keep showing its last window
+					readings = nil
+					drained = true
+					continue
+				}
+				win.Add(r)
+				dirty = true
+			default:
+				drained = true
+			}
+		}
+		if dirty && opts.Width > 0 && opts.Height > 0 {
+			tex.SetFromRGBA(stripchart.Render(win, opts))
+			dirty = false
+		}
+
+		// Clear the color, depth, and stencil buffers
+		a.Gls().Clear(gls.COLOR_BUFFER_BIT | gls.DEPTH_BUFFER_BIT | gls.STENCIL_BUFFER_BIT)
+
+		// Render scene
+		if err := rend.Render(scene, cam); err != nil {
+			panic(err)
+		}
+
+		// Update GUI timers
+		gui.Manager().TimerManager.ProcessTimers()
+
+		// Control and update FPS
+		rater.Wait()
+	})
+}
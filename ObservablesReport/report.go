@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"hackathon/observables"
+	"hackathon/scenario"
+	"hackathon/systems"
+)
+
+// ObservablesReport prints the expectation values and uncertainties of a
+// scenario's state, and optionally its overlap with another scenario's state.
+func main() {
+	path := flag.String("scenario", "", "YAML scenario file describing the state ψ")
+	overlap := flag.String("overlap", "", "scenario file describing φ for the overlap ⟨φ|ψ⟩")
+	method := flag.String("method", "quadrature", "integration method: quadrature, montecarlo or samples")
+	n := flag.Int("n", 0, "Simpson intervals per axis, or Monte Carlo points (default 64 or 200000)")
+	t := flag.Float64("t", 0, "simulated time to evaluate at")
+	flag.Parse()
+	if *path == "" {
+		log.Fatal("usage: ObservablesReport -scenario file.yaml [-overlap other.yaml] [-method quadrature|montecarlo|samples] [-n N] [-t time]")
+	}
+
+	scn, err := scenario.Load(*path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sys, err := scn.Build()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	in, err := integrator(scn, sys, *method, *n)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s: %s at t = %g\n", scn.Name, *method, *t)
+	if err := observables.Compute(sys, in, *t, scn.Hamiltonian()).WriteText(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+
+	if *overlap != "" {
+		other, err := scenario.Load(*overlap)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := sameSpace(scn, other); err != nil {
+			log.Fatal(err)
+		}
+		phi, err := other.Build()
+		if err != nil {
+			log.Fatal(err)
+		}
+		o := observables.Overlap(phi, sys, in, *t)
+		fmt.Printf("<phi|psi>  %14.6g %+.6gi  ± %.2g  (phi: %s)\n", real(o.Value), imag(o.Value), o.Error, other.Name)
+	}
+}
+
+// sameSpace checks that φ and ψ live in the same space, as the overlap is
+// integrated over ψ's bounds alone.
+func sameSpace(psi, phi *scenario.Scenario) error {
+	if len(phi.System.Width) != len(psi.System.Width) {
+		return fmt.Errorf("overlap: %s is %dD but %s is %dD", phi.Name, len(phi.System.Width), psi.Name, len(psi.System.Width))
+	}
+	for i, w := range psi.System.Width {
+		if phi.System.Width[i] != w {
+			return fmt.Errorf("overlap: %s has widths %v but %s has %v", phi.Name, phi.System.Width, psi.Name, psi.System.Width)
+		}
+	}
+	return nil
+}
+
+// integrator builds the requested integration method over the well. The
+// samples method reuses the scenario's own sampling strategy, so the numbers
+// match what the viewers' overlays show.
+func integrator(scn *scenario.Scenario, sys systems.System, method string, n int) (observables.Integrator, error) {
+	min, max := sys.Bounds()
+	switch method {
+	case "quadrature":
+		if n == 0 {
+			n = 64
+		}
+		return observables.NewQuadrature(min, max, n), nil
+	case "montecarlo":
+		if n == 0 {
+			n = 200000
+		}
+		return observables.MonteCarlo(min, max, n, scn.Sampling.Seed), nil
+	case "samples":
+		return scn.SampleSet(sys, scn.Sample(sys, 0), 0), nil
+	}
+	return nil, fmt.Errorf("unknown method %q", method)
+}
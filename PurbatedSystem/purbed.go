@@ -1,8 +1,11 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"image"
+	"log"
 	"math"
-	"math/rand"
 	"time"
 
 	"github.com/g3n/engine/app"
@@ -16,13 +19,59 @@ import (
 	"github.com/g3n/engine/material"
 	"github.com/g3n/engine/math32"
 	"github.com/g3n/engine/renderer"
+	"github.com/g3n/engine/texture"
 	"github.com/g3n/engine/util"
 	"github.com/g3n/engine/util/helper"
 	"github.com/g3n/engine/window"
 	"gonum.org/v1/gonum/stat"
+
+	"hackathon/axes"
+	"hackathon/axes/axes3d"
+	"hackathon/colormap"
+	"hackathon/controls"
+	"hackathon/perturbation"
+	"hackathon/scenario"
+	"hackathon/stripchart"
+	"hackathon/systems"
+	"voltmeter/acquire"
 )
 
+// scn is the scenario describing this run
+var scn *scenario.Scenario
+
+// cmap is the colormap selected by the scenario
+var cmap colormap.Map = colormap.RedBlue
+
+// sys is the system the scenario describes
+var sys systems.System
+
+// evolution is sys when the scenario drives it, nil otherwise
+var evolution *perturbation.Evolution
+
+// plotWidth and plotHeight are the size of the level population plot
+const plotWidth, plotHeight = 420, 220
+
+// historyLength is how many frames of populations the plot keeps
+const historyLength = 3000
+
+// history holds the population of every level at each recent frame, for the plot
+var history struct {
+	t           []float64
+	populations [][]float64
+}
+
 func main() {
+	// Load the scenario describing this run, with the voltmeter flags for any inputs it binds
+	settings := acquire.RegisterFlags(flag.CommandLine)
+	scn = scenario.FromFlags(defaultScenario())
+	if len(scn.System.Width) != 2 {
+		log.Fatalf("this viewer shows 2D systems, scenario %q has %d dimensions", scn.Name, len(scn.System.Width))
+	}
+	cmap = scn.ColorMap()
+	if err := build(); err != nil {
+		log.Fatal(err)
+	}
+
 	// Create application and scene
 	a := app.App()
 	scene := core.NewNode()
@@ -33,12 +82,21 @@ func main() {
 
 	// Create perspective camera
 	cam := camera.New(1)
-	cam.SetPosition(0, 0, 3)
+	cam.SetPosition(float32(scn.Camera.Position[0]), float32(scn.Camera.Position[1]), float32(scn.Camera.Position[2]))
 	scene.Add(cam)
 
 	// Set up orbit control for the camera
 	camera.NewOrbitControl(cam)
 
+	// A driven system plots the population of each level against time in the corner
+	var plot *gui.Image
+	var plotTex *texture.Texture2D
+	if evolution != nil {
+		plotTex = texture.NewTexture2DFromRGBA(populationPlot())
+		plot = gui.NewImageFromTex(plotTex)
+		scene.Add(plot)
+	}
+
 	// Set up callback to update viewport and camera aspect ratio when the window is resized
 	onResize := func(evname string, ev interface{}) {
 		// Get framebuffer size and update viewport accordingly
@@ -46,21 +104,29 @@ func main() {
 		a.Gls().Viewport(0, 0, int32(width), int32(height))
 		// Update the camera's aspect ratio
 		cam.SetAspect(float32(width) / float32(height))
+		// Keep the plot in the bottom left corner
+		if plot != nil {
+			plot.SetPosition(10, float32(height-plotHeight-10))
+		}
 	}
 	a.Subscribe(window.OnWindowSize, onResize)
 	onResize("", nil)
 
-	// axis length
-	//TODO function call to set maxs
-	xLength := float64(15)
-	zLength := float64(1)
-	yLength := float64(15)
+	// Axes span the sampled region, with the wavefunction drawn up the screen
+	graph := createGraph(scene, axisLayout(), scn.Axes.Ticks)
+	cloud := core.NewNode()
+	scene.Add(cloud)
+	points := scn.Sample(sys, 0)
+	mats, meshs := plotPoints(cloud, points)
+	scale := heightScale(points)
 
-	//if max is 10 min is -5
+	// Add the control panel for editing the scenario live
+	panel := controls.New(scn)
+	scene.Add(panel)
 
-	createGraph(scene, xLength, zLength, yLength)
-	points := generateRandomCoords(10000, 0, xLength, 0, yLength, 0, 0)
-	mats, meshs := plotPoints(scene, points)
+	// Voltmeter channels bound by the scenario steer it alongside the panel,
+	// so ADC0 can set v_0 live
+	knobs := controls.KnobsFromFlags(scn, settings)
 
 	// Create and add lights to the scene
 	scene.Add(light.NewAmbient(&math32.Color{1.0, 1.0, 1.0}, 0.8))
@@ -88,11 +154,59 @@ func main() {
 			panic(err)
 		}
 
+		// Apply edits made in the control panel or by the voltmeter since the last frame
+		change := panel.Poll() | knobs.Poll()
+		if change.Has(controls.Rebuild) {
+			// A setting the system cannot take keeps the last one that
+			// worked, and a driven system starts again from the new scenario
+			if err := build(); err != nil {
+				log.Print(err)
+			} else if evolution != nil {
+				t = 0
+			}
+		}
+		if change.Has(controls.Resample) {
+			scene.Remove(graph)
+			graph.DisposeChildren(true)
+			graph = createGraph(scene, axisLayout(), scn.Axes.Ticks)
+			scene.Remove(cloud)
+			cloud.DisposeChildren(true)
+			cloud = core.NewNode()
+			scene.Add(cloud)
+			points = scn.Sample(sys, t)
+			mats, meshs = plotPoints(cloud, points)
+			scale = heightScale(points)
+		}
+		if change.Has(controls.Recolor) {
+			cmap = scn.ColorMap()
+		}
+
+		// Every point shares one clock, as a driven system's coefficients
+		// are stepped through time together
+		t = t + scn.Solver.TimeStep
+		if evolution != nil {
+			exact, _ := evolution.Populations(t)
+			history.t = append(history.t, t)
+			history.populations = append(history.populations, exact)
+			if len(history.t) > historyLength {
+				history.t = history.t[1:]
+				history.populations = history.populations[1:]
+			}
+		}
 		for i := 0; i < len(points); i++ {
-			t = t + 1.0/10.0
-			val := calculateWaveFunction(points[i][0], points[i][1], t)
-			mats[i].SetColor(GenerateColorOnGradient((0 + val*(1))))
-			meshs[i].SetPosition(float32(points[i][0]), float32(val), float32(points[i][1]))
+			val := sys.Evaluate(points[i][0], points[i][1], 0, t)
+			height, colour := real(val), systems.Quantity(val, scn.Display)
+			if evolution != nil {
+				height = colour * scale
+				colour = height
+			}
+			mats[i].SetColor(GenerateColorOnGradient((0 + colour*(1))))
+			meshs[i].SetPosition(float32(points[i][0]), float32(height), float32(points[i][1]))
+		}
+
+		// Redraw the plot twice a second
+		if _, _, ok := rater.FPS(time.Second / 2); ok && plot != nil {
+			plotTex.SetFromRGBA(populationPlot())
 		}
 
 		// Update GUI timers
@@ -103,42 +217,97 @@ func main() {
 	})
 }
 
-func calculateWaveFunction(x, y, t float64) float64 {
-	// Constants for the infinite square well problem
-	a := 10.0          // width of the well
-	ny, nz := 1.0, 1.0 // quantum numbers for each dimension
-	v_0 := 6.0 * 10e-6 // potential energy of the well
+// defaultScenario is the perturbed 2D well this viewer shows when no scenario
+// file is given
+func defaultScenario() *scenario.Scenario {
+	return &scenario.Scenario{
+		Name: "perturbed square well",
+		System: scenario.System{
+			Kind:      "infinite-well",
+			Width:     []float64{10, 10},                                     // width of the well
+			Potential: scenario.Potential{Kind: "constant", V0: 6.0 * 10e-6}, // potential energy of the well
+			Quantum:   []int{1, 1},                                           // quantum numbers for each dimension
+		},
+		Solver:   scenario.Solver{TimeStep: 1.0 / 10.0},
+		Sampling: scenario.Sampling{Strategy: "random", Points: 10000, Extent: []float64{15, 15}},
+		Display:  "real",
+	}
+}
+
+// build builds the system the scenario describes and, when it is driven,
+// logs what theory predicts for the transition from the starting level
+func build() error {
+	next, err := scn.Build()
+	if err != nil {
+		return err
+	}
+	sys = next
+	evolution, _ = next.(*perturbation.Evolution)
+	if evolution == nil {
+		return nil
+	}
+	history.t, history.populations = nil, nil
 
-	realPartZ := 0.0
-	if x <= 10 && y <= 10 {
-		realPartZ = ((2.0/a)*math.Sin(nz*math.Pi*x/a)*math.Sin(ny*math.Pi*y/a) - 8.0*v_0)
+	from := 0
+	for i, n := range evolution.Levels {
+		if n == scn.System.States()[0].N {
+			from = i
+		}
+	}
+	if to, detuning := evolution.Resonant(from); to >= 0 {
+		log.Printf("%v to %v: detuning %.4g, Rabi frequency %.4g, golden rule rate %.4g",
+			evolution.Levels[from], evolution.Levels[to], detuning, evolution.RabiFrequency(from, to), evolution.GoldenRule(from, to))
 	}
+	return nil
+}
 
-	// Combine the real and imaginary parts to get the full wave function
-	waveFunction := (realPartZ)
+// heightScale maps a driven system's quantity onto the screen so its peak
+// as it starts out is drawn at height 1, where the colormap tops out
+func heightScale(points [][]float64) float64 {
+	if evolution == nil {
+		return 1
+	}
+	peak := 0.0
+	for _, p := range points {
+		peak = math.Max(peak, math.Abs(systems.Quantity(evolution.Basis.Evaluate(p[0], p[1], 0, 0), scn.Display)))
+	}
+	if peak == 0 {
+		return 1
+	}
+	return 1 / peak
+}
 
-	return waveFunction
+// populationPlot draws the population |c_n|² of each level over the frames
+// shown so far
+func populationPlot() *image.RGBA {
+	var traces []stripchart.Trace
+	for i, n := range evolution.Levels {
+		trace := stripchart.Trace{Label: fmt.Sprint(n[:len(scn.System.Width)]), Color: i}
+		for j, at := range history.t {
+			trace.X, trace.Y = append(trace.X, at), append(trace.Y, history.populations[j][i])
+		}
+		traces = append(traces, trace)
+	}
+	pane := stripchart.Pane{Title: scn.System.Drive.Kind + " drive", XLabel: "t", Traces: traces}
+	return stripchart.Plot([]stripchart.Pane{pane}, stripchart.Options{Width: plotWidth, Height: plotHeight, Ticks: 3})
+}
+
+// axisLayout returns the scenario's axes with the wavefunction drawn up the screen
+func axisLayout() [3]axes.Axis {
+	layout := scn.AxisLayout()
+	if scn.Axes.Z.Label == "" {
+		layout[2].Label, layout[2].Unit = "ψ", ""
+	}
+	return layout
 }
 
-func createGraph(scene *core.Node, xLength, zLength, yLength float64) {
-	// x axis
-	geomX := geometry.NewBox(float32(xLength), 0.05, 0.05)
-	matX := material.NewStandard(math32.NewColor("DarkBlue"))
-	meshX := graphic.NewMesh(geomX, matX)
-	meshX.SetPosition(float32(xLength/2), 0, 0)
-	scene.Add(meshX)
-	// z axis
-	geomZ := geometry.NewBox(0.05, float32(zLength), 0.05)
-	matZ := material.NewStandard(math32.NewColor("DarkBlue"))
-	meshZ := graphic.NewMesh(geomZ, matZ)
-	meshZ.SetPosition(0, float32(zLength/2), 0)
-	scene.Add(meshZ)
-	// y axis
-	geomY := geometry.NewBox(0.05, 0.05, float32(yLength))
-	matY := material.NewStandard(math32.NewColor("DarkBlue"))
-	meshY := graphic.NewMesh(geomY, matY)
-	meshY.SetPosition(0, 0, float32(yLength/2))
-	scene.Add(meshY)
+// createGraph draws labelled axes for the physical x, y and z ranges, with z up the screen
+func createGraph(scene *core.Node, layout [3]axes.Axis, ticks int) *core.Node {
+	cfg := axes3d.DefaultConfig([3]axes.Axis{layout[0], layout[2], layout[1]})
+	cfg.Ticks = ticks
+	graph := axes3d.New(cfg)
+	scene.Add(graph)
+	return graph
 }
 
 func plotPoints(scene *core.Node, points [][]float64) ([]*material.Standard, []*graphic.Mesh) {
@@ -157,36 +326,9 @@ func plotPoints(scene *core.Node, points [][]float64) ([]*material.Standard, []*
 	return mats, meshs
 }
 
-// generateRandomCoords generates random arrays of float64 within specified bounds
-func generateRandomCoords(numCoords int, minX, maxX, minY, maxY, minZ, maxZ float64) [][]float64 {
-	// Create the 2D array to store the random arrays
-	result := make([][]float64, numCoords)
-	r := rand.New(rand.NewSource(38))
-
-	// Generate random values for each array
-	for i := 0; i < numCoords; i++ {
-		result[i] = make([]float64, 3)
-		result[i][0] = minX + r.Float64()*(maxX-minX) // Random value for x
-		result[i][1] = minY + r.Float64()*(maxY-minY) // Random value for y
-		result[i][2] = minZ + r.Float64()*(maxZ-minZ) // Random value for z
-	}
-	return result
-}
-
-// GenerateColorOnGradient generates a color on a gradient from red to blue based on the input value (0 to 1)
+// GenerateColorOnGradient generates a color on the scenario's colormap based on the input value (0 to 1)
 func GenerateColorOnGradient(value float64) *math32.Color {
-	// Ensure the value is within the range of 0 to 1
-	if value < 0 {
-		value = 0
-	} else if value > 1 {
-		value = 1
-	}
-
-	// Interpolate between red and blue based on the input value
-	red := 1 - float32(value)
-	blue := float32(value)
-	green := float32(0)
-
+	red, green, blue := cmap(value)
 	return &math32.Color{R: red, G: green, B: blue}
 }
 func NormalizeVals(vals []float64) []float64 {
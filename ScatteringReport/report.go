@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"image/png"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"hackathon/scattering"
+	"hackathon/scenario"
+	"hackathon/stripchart"
+)
+
+// ScatteringReport works out how much of a wave sent at a scenario's
+// barrier is transmitted and reflected, exactly for plane waves across a
+// range of energies and by evolving packets in time, and writes both as
+// CSV with a PNG plot alongside.
+func main() {
+	out := flag.String("out", "scattering_report", "directory to write results to")
+	emin := flag.Float64("emin", 0, "lowest energy of the sweep, a tenth of the scenario's when zero")
+	emax := flag.Float64("emax", 0, "highest energy of the sweep, four times the scenario's when zero")
+	n := flag.Int("n", 400, "plane wave energies in the sweep")
+	packets := flag.Int("packets", 12, "1D packets evolved across the sweep, none when zero")
+	width := flag.Int("width", 1200, "width of the plot in pixels")
+	height := flag.Int("height", 900, "height of the plot in pixels")
+	scn := scenario.FromFlags(defaultScenario())
+	if scn.System.Kind != "scattering" {
+		log.Fatalf("scenario %q is a %s, not a scattering system", scn.Name, scn.System.Kind)
+	}
+	hbar, mass := scn.System.Constants()
+	profile := scn.System.Barrier.Profile()
+	incident := scn.System.Incident
+	if *emin <= 0 {
+		*emin = incident.Energy / 10
+	}
+	if *emax <= *emin {
+		*emax = 4 * incident.Energy
+	}
+
+	transmitted, reflected, err := scattering.Coefficients(profile, incident.Energy, hbar, mass)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s: plane wave at E = %g: T = %.6f, R = %.6f\n", scn.Name, incident.Energy, transmitted, reflected)
+
+	// The scenario's own packet, in as many dimensions as it has
+	if incident.Kind == "packet" {
+		sys, err := scn.Build()
+		if err != nil {
+			log.Fatal(err)
+		}
+		sim := sys.(*scattering.Simulation)
+		stop := sim.Run(1e-3, 4*(sim.Arrival()+sim.Duration()))
+		t, r := sim.Measure()
+		fmt.Printf("packet of spread %g stopped at t = %.4g: T = %.6f, R = %.6f with %.2g still inside, plane waves averaged over its energies T = %.6f\n",
+			incident.Spread, stop, t, r, 1-t-r, averaged(profile, incident.Energy, incident.Spread, hbar, mass))
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	write := func(name string, err error) {
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("wrote %s", filepath.Join(*out, name))
+	}
+
+	sweep, err := planeWaves(profile, *emin, *emax, *n, hbar, mass)
+	if err != nil {
+		log.Fatal(err)
+	}
+	write("transmission.csv", writeSweep(filepath.Join(*out, "transmission.csv"), sweep))
+
+	runs := packetRuns(scn, profile, *emin, *emax, *packets, hbar, mass)
+	if len(runs) > 0 {
+		write("packets.csv", writePackets(filepath.Join(*out, "packets.csv"), runs))
+	}
+	opts := stripchart.Options{Width: *width, Height: *height}
+	write("transmission.png", writePNG(filepath.Join(*out, "transmission.png"), panes(sweep, runs), opts))
+}
+
+// defaultScenario is the double barrier swept when no scenario file is
+// given, a packet narrow in energy sent at its first resonance.
+func defaultScenario() *scenario.Scenario {
+	return &scenario.Scenario{
+		Name: "double barrier",
+		System: scenario.System{
+			Kind:     "scattering",
+			Width:    []float64{120},
+			Barrier:  scenario.Barrier{Shape: "double", Height: 1.5, Thickness: 1, Gap: 3},
+			Incident: scenario.Incident{Kind: "packet", Energy: 0.2732, Spread: 6},
+		},
+	}
+}
+
+// point is the plane wave coefficients at one energy.
+type point struct {
+	energy, transmitted, reflected float64
+}
+
+// planeWaves sweeps the transfer matrix coefficients over n energies.
+func planeWaves(p scattering.Profile, emin, emax float64, n int, hbar, mass float64) ([]point, error) {
+	var sweep []point
+	for i := 0; i < n; i++ {
+		e := emin + (emax-emin)*float64(i)/float64(max(n-1, 1))
+		t, r, err := scattering.Coefficients(p, e, hbar, mass)
+		if err != nil {
+			return nil, err
+		}
+		sweep = append(sweep, point{e, t, r})
+	}
+	return sweep, nil
+}
+
+// run is a packet evolved through the barrier, with the plane wave
+// transmission averaged over its energies to compare against.
+type run struct {
+	point
+	averaged, stop float64
+}
+
+// packetRuns sends 1D packets of the scenario's spread and start at the
+// barrier at n energies across the sweep. A packet the grid cannot carry
+// is logged and left out.
+func packetRuns(scn *scenario.Scenario, p scattering.Profile, emin, emax float64, n int, hbar, mass float64) []run {
+	var runs []run
+	for i := 0; i < n; i++ {
+		e := emin + (emax-emin)*(float64(i)+0.5)/float64(n)
+		packet := scn.System.Incident.Packet()
+		packet.Energy, packet.Start[1], packet.Angle = e, 0, 0
+		grid := scattering.Grid{Min: [2]float64{-scn.System.Width[0] / 2}, Max: [2]float64{scn.System.Width[0] / 2}}
+		sim, err := scattering.NewSimulation(p, packet, grid.Fit(p, packet, hbar, mass, 1), hbar, mass)
+		if err != nil {
+			log.Printf("packet at E = %g: %v", e, err)
+			continue
+		}
+		stop := sim.Run(1e-3, 4*(sim.Arrival()+sim.Duration()))
+		t, r := sim.Measure()
+		runs = append(runs, run{point{e, t, r}, averaged(p, e, packet.Spread, hbar, mass), stop})
+		fmt.Printf("packet at E = %-8.4g T = %.6f, R = %.6f, averaged plane waves T = %.6f\n", e, t, r, runs[len(runs)-1].averaged)
+	}
+	return runs
+}
+
+// averaged returns the plane wave transmission weighted by a Gaussian
+// packet's momentum distribution, |φ(k)|² ∝ exp(-2σ²(k-k₀)²), which is what
+// the packet as a whole should transmit.
+func averaged(p scattering.Profile, energy, spread, hbar, mass float64) float64 {
+	k0 := math.Sqrt(2*mass*energy) / hbar
+	dk := 1 / (2 * spread)
+	sum, weights := 0.0, 0.0
+	for i := -300; i <= 300; i++ {
+		k := k0 + 6*dk*float64(i)/300
+		if k <= 0 {
+			continue
+		}
+		t, _, err := scattering.Coefficients(p, hbar*hbar*k*k/(2*mass), hbar, mass)
+		if err != nil {
+			continue
+		}
+		w := math.Exp(-2 * spread * spread * (k - k0) * (k - k0))
+		sum, weights = sum+w*t, weights+w
+	}
+	return sum / weights
+}
+
+func writeSweep(path string, sweep []point) error {
+	rows := [][]string{{"Energy", "Transmitted", "Reflected"}}
+	for _, p := range sweep {
+		rows = append(rows, []string{num(p.energy), num(p.transmitted), num(p.reflected)})
+	}
+	return writeCSV(path, rows)
+}
+
+func writePackets(path string, runs []run) error {
+	rows := [][]string{{"Energy", "Transmitted", "Reflected", "Averaged plane wave transmission", "Stopped at"}}
+	for _, r := range runs {
+		rows = append(rows, []string{num(r.energy), num(r.transmitted), num(r.reflected), num(r.averaged), num(r.stop)})
+	}
+	return writeCSV(path, rows)
+}
+
+func writeCSV(path string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(file)
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func num(v float64) string {
+	return strconv.FormatFloat(v, 'g', 8, 64)
+}
+
+// panes plots T and R against energy, the plane wave sweep as lines and
+// the packets as crosses.
+func panes(sweep []point, runs []run) []stripchart.Pane {
+	t := stripchart.Pane{Title: "transmitted", XLabel: "E"}
+	r := stripchart.Pane{Title: "reflected", XLabel: "E"}
+	line := func(get func(point) float64) stripchart.Trace {
+		tr := stripchart.Trace{Label: "plane wave", Color: 1}
+		for _, p := range sweep {
+			tr.X, tr.Y = append(tr.X, p.energy), append(tr.Y, get(p))
+		}
+		return tr
+	}
+	t.Traces = append(t.Traces, line(func(p point) float64 { return p.transmitted }))
+	r.Traces = append(r.Traces, line(func(p point) float64 { return p.reflected }))
+	if len(runs) > 0 {
+		tp := stripchart.Trace{Label: "packets", Color: 0, Marks: true}
+		ta := stripchart.Trace{Label: "averaged plane waves", Color: 3, Marks: true}
+		rp := stripchart.Trace{Label: "packets", Color: 0, Marks: true}
+		for _, run := range runs {
+			tp.X, tp.Y = append(tp.X, run.energy), append(tp.Y, run.transmitted)
+			ta.X, ta.Y = append(ta.X, run.energy), append(ta.Y, run.averaged)
+			rp.X, rp.Y = append(rp.X, run.energy), append(rp.Y, run.reflected)
+		}
+		t.Traces = append(t.Traces, ta, tp)
+		r.Traces = append(r.Traces, rp)
+	}
+	return []stripchart.Pane{t, r}
+}
+
+func writePNG(path string, panes []stripchart.Pane, opts stripchart.Options) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, stripchart.Plot(panes, opts)); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"hackathon/analysis"
+	"hackathon/stripchart"
+	"voltmeter/calibration"
+)
+
+// SignalAnalysis filters a voltmeter recording and works out its spectrum,
+// RMS, peaks and the correlation between two channels, writing each result
+// as CSV with PNG plots alongside.
+func main() {
+	recording := flag.String("recording", "", "recording written by the logger, CSV or binary, optionally gzipped")
+	calPath := flag.String("calibration", "", "calibration JSON, needed only for old recordings of raw counts")
+	out := flag.String("out", "signal_analysis", "directory to write results to")
+	average := flag.Int("average", 5, "readings in the moving average")
+	median := flag.Int("median", 5, "readings in the median filter")
+	cutoff := flag.Float64("cutoff", 0.5, "low-pass cutoff frequency in Hz")
+	prominence := flag.Float64("prominence", 0.05, "volts a peak must stand above its surroundings")
+	distance := flag.Duration("distance", 0, "minimum time between peaks")
+	a := flag.Int("a", 0, "first ADC pin to correlate")
+	b := flag.Int("b", 1, "second ADC pin to correlate")
+	maxLag := flag.Duration("max-lag", 0, "largest lag to correlate at, a tenth of the recording when zero")
+	width := flag.Int("width", 1200, "width of the plots in pixels")
+	height := flag.Int("height", 900, "height of the plots in pixels")
+	flag.Parse()
+	if *recording == "" {
+		log.Fatal("usage: SignalAnalysis -recording voltage_readings-<start>.csv [-out dir] [-average N] [-median N] [-cutoff Hz] [-prominence V] [-a pin -b pin]")
+	}
+
+	cal := calibration.Default()
+	if *calPath != "" {
+		var err error
+		if cal, err = calibration.Load(*calPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	series, err := analysis.Load(*recording, cal)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatal(err)
+	}
+
+	results := make([]result, len(series))
+	for i, s := range series {
+		results[i] = analyse(s, *average, *median, *cutoff, *prominence, distance.Seconds())
+		results[i].print()
+	}
+
+	var corr *analysis.Correlation
+	sa, oka := find(series, *a)
+	sb, okb := find(series, *b)
+	if oka && okb && *a != *b {
+		c := correlate(sa, sb, maxLag.Seconds())
+		lag, r := c.Best()
+		when := "behind"
+		if lag < 0 {
+			when, lag = "ahead", -lag
+		}
+		fmt.Printf("%s vs %s: best match %.3f with %s %.4g s %s\n", sa.Name, sb.Name, r, sb.Name, lag, when)
+		corr = &c
+	} else {
+		log.Printf("not correlating: the recording needs both ADC%d and ADC%d", *a, *b)
+	}
+
+	opts := stripchart.Options{Width: *width, Height: *height}
+	write := func(name string, err error) {
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("wrote %s", filepath.Join(*out, name))
+	}
+	write("summary.csv", writeSummary(filepath.Join(*out, "summary.csv"), results))
+	write("filtered.csv", writeFiltered(filepath.Join(*out, "filtered.csv"), results))
+	write("spectrum.csv", writeSpectrum(filepath.Join(*out, "spectrum.csv"), results))
+	write("peaks.csv", writePeaks(filepath.Join(*out, "peaks.csv"), results))
+	write("filtered.png", writePNG(filepath.Join(*out, "filtered.png"), filteredPanes(results), opts))
+	write("spectrum.png", writePNG(filepath.Join(*out, "spectrum.png"), spectrumPanes(results), opts))
+	if corr != nil {
+		write("correlation.csv", writeCorrelation(filepath.Join(*out, "correlation.csv"), *corr))
+		opts.Height /= 2
+		write("correlation.png", writePNG(filepath.Join(*out, "correlation.png"), correlationPanes(sa, sb, *corr), opts))
+	}
+}
+
+// result holds everything worked out for one channel.
+type result struct {
+	analysis.Series
+	interval               float64
+	mean, rms, ac          float64
+	min, max               float64
+	average, median, low   []float64
+	spectrum               analysis.Spectrum
+	peaks                  []analysis.Peak
+	dominant, domAmplitude float64
+}
+
+// analyse filters a channel and measures it.
+func analyse(s analysis.Series, average, median int, cutoff, prominence, distance float64) result {
+	r := result{Series: s, interval: s.Interval(), mean: analysis.Mean(s.V)}
+	r.rms, r.ac = analysis.RMS(s.V)
+	r.min, r.max = s.V[0], s.V[0]
+	for _, v := range s.V {
+		r.min, r.max = min(r.min, v), max(r.max, v)
+	}
+	r.average = analysis.MovingAverage(s.V, average)
+	r.median = analysis.Median(s.V, median)
+	r.low = analysis.LowPass(s.T, s.V, cutoff)
+	r.peaks = analysis.Peaks(s.T, s.V, prominence, distance)
+
+	// The spectrum needs even spacing, which the logger only approximates
+	if r.interval > 0 {
+		r.spectrum = analysis.NewSpectrum(s.Resample(s.T[0], s.T[len(s.T)-1], r.interval), r.interval)
+		r.dominant, r.domAmplitude = r.spectrum.Dominant()
+	}
+	return r
+}
+
+func (r result) print() {
+	fmt.Printf("%s: %d readings every %.4g s, mean %.4f V, RMS %.4f V (AC %.4f V), range %.4f to %.4f V, %d peaks",
+		r.Name, len(r.V), r.interval, r.mean, r.rms, r.ac, r.min, r.max, len(r.peaks))
+	if r.dominant > 0 {
+		fmt.Printf(", strongest at %.4g Hz (%.4f V)", r.dominant, r.domAmplitude)
+	}
+	fmt.Println()
+}
+
+// find returns the series of an ADC pin.
+func find(series []analysis.Series, pin int) (analysis.Series, bool) {
+	for _, s := range series {
+		if s.Pin == pin {
+			return s, true
+		}
+	}
+	return analysis.Series{}, false
+}
+
+// correlate resamples two channels over the time both were recorded, at the
+// coarser of their rates, and correlates them.
+func correlate(a, b analysis.Series, maxLag float64) analysis.Correlation {
+	from, to := max(a.T[0], b.T[0]), min(a.T[len(a.T)-1], b.T[len(b.T)-1])
+	dt := max(a.Interval(), b.Interval())
+	if to <= from || dt <= 0 {
+		return analysis.Correlation{}
+	}
+	if maxLag <= 0 {
+		maxLag = (to - from) / 10
+	}
+	return analysis.CrossCorrelate(a.Resample(from, to, dt), b.Resample(from, to, dt), dt, maxLag)
+}
+
+func writeSummary(path string, results []result) error {
+	rows := [][]string{{"Channel", "Readings", "Interval (s)", "Mean (V)", "RMS (V)", "AC RMS (V)", "Min (V)", "Max (V)", "Peaks", "Dominant frequency (Hz)", "Dominant amplitude (V)"}}
+	for _, r := range results {
+		rows = append(rows, []string{r.Name, strconv.Itoa(len(r.V)), num(r.interval), num(r.mean), num(r.rms), num(r.ac),
+			num(r.min), num(r.max), strconv.Itoa(len(r.peaks)), num(r.dominant), num(r.domAmplitude)})
+	}
+	return writeCSV(path, rows)
+}
+
+func writeFiltered(path string, results []result) error {
+	rows := [][]string{{"Channel", "Elapsed (s)", "Voltage (V)", "Moving average (V)", "Median (V)", "Low-pass (V)"}}
+	for _, r := range results {
+		for i := range r.V {
+			rows = append(rows, []string{r.Name, num(r.T[i]), num(r.V[i]), num(r.average[i]), num(r.median[i]), num(r.low[i])})
+		}
+	}
+	return writeCSV(path, rows)
+}
+
+func writeSpectrum(path string, results []result) error {
+	rows := [][]string{{"Channel", "Frequency (Hz)", "Amplitude (V)"}}
+	for _, r := range results {
+		for i := range r.spectrum.Freq {
+			rows = append(rows, []string{r.Name, num(r.spectrum.Freq[i]), num(r.spectrum.Amplitude[i])})
+		}
+	}
+	return writeCSV(path, rows)
+}
+
+func writePeaks(path string, results []result) error {
+	rows := [][]string{{"Channel", "Elapsed (s)", "Voltage (V)", "Prominence (V)"}}
+	for _, r := range results {
+		for _, p := range r.peaks {
+			rows = append(rows, []string{r.Name, num(p.T), num(p.V), num(p.Prominence)})
+		}
+	}
+	return writeCSV(path, rows)
+}
+
+func writeCorrelation(path string, c analysis.Correlation) error {
+	rows := [][]string{{"Lag (s)", "Correlation"}}
+	for i := range c.Lag {
+		rows = append(rows, []string{num(c.Lag[i]), num(c.R[i])})
+	}
+	return writeCSV(path, rows)
+}
+
+// writeCSV writes rows, the first being the header.
+func writeCSV(path string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(file)
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func num(v float64) string {
+	return strconv.FormatFloat(v, 'g', 8, 64)
+}
+
+// filteredPanes plots each channel with its filtered versions and peaks.
+func filteredPanes(results []result) []stripchart.Pane {
+	var panes []stripchart.Pane
+	for _, r := range results {
+		p := stripchart.Pane{Title: r.Name + " (V)", XLabel: "s", Traces: []stripchart.Trace{
+			{Label: "raw", X: r.T, Y: r.V, Color: 1},
+			{Label: "moving average", X: r.T, Y: r.average, Color: 2},
+			{Label: "median", X: r.T, Y: r.median, Color: 4},
+			{Label: "low-pass", X: r.T, Y: r.low, Color: 3},
+		}}
+		peaks := stripchart.Trace{Label: "peaks", Color: 0, Marks: true}
+		for _, pk := range r.peaks {
+			peaks.X, peaks.Y = append(peaks.X, pk.T), append(peaks.Y, pk.V)
+		}
+		p.Traces = append(p.Traces, peaks)
+		panes = append(panes, p)
+	}
+	return panes
+}
+
+// spectrumPanes plots each channel's amplitude spectrum.
+func spectrumPanes(results []result) []stripchart.Pane {
+	var panes []stripchart.Pane
+	for _, r := range results {
+		panes = append(panes, stripchart.Pane{Title: r.Name + " amplitude (V)", XLabel: "Hz", Traces: []stripchart.Trace{
+			{X: r.spectrum.Freq, Y: r.spectrum.Amplitude, Color: r.Pin},
+		}})
+	}
+	return panes
+}
+
+// correlationPanes plots the correlation coefficient against lag.
+func correlationPanes(a, b analysis.Series, c analysis.Correlation) []stripchart.Pane {
+	return []stripchart.Pane{{Title: fmt.Sprintf("correlation of %s with %s", b.Name, a.Name), XLabel: "s", Traces: []stripchart.Trace{
+		{X: c.Lag, Y: c.R, Color: b.Pin},
+	}}}
+}
+
+func writePNG(path string, panes []stripchart.Pane, opts stripchart.Options) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, stripchart.Plot(panes, opts)); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
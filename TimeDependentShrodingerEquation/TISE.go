@@ -1,9 +1,9 @@
 package main
 
 import (
+	"log"
 	"math"
 	"math/cmplx"
-	"math/rand"
 	"time"
 
 	"github.com/g3n/engine/app"
@@ -21,9 +21,28 @@ import (
 	"github.com/g3n/engine/util/helper"
 	"github.com/g3n/engine/window"
 	"gonum.org/v1/gonum/stat"
+
+	"hackathon/axes"
+	"hackathon/axes/axes3d"
+	"hackathon/colormap"
+	"hackathon/scenario"
+	"hackathon/systems"
 )
 
+// scn is the scenario describing this run
+var scn *scenario.Scenario
+
+// cmap is the colormap selected by the scenario
+var cmap colormap.Map = colormap.RedBlue
+
 func main() {
+	// Load the scenario describing this run
+	scn = scenario.FromFlags(defaultScenario())
+	if len(scn.System.Width) != 3 {
+		log.Fatalf("this viewer shows 3D systems, scenario %q has %d dimensions", scn.Name, len(scn.System.Width))
+	}
+	cmap = scn.ColorMap()
+
 	// Create application and scene
 	a := app.App()
 	scene := core.NewNode()
@@ -34,7 +53,7 @@ func main() {
 
 	// Create perspective camera
 	cam := camera.New(1)
-	cam.SetPosition(0, 0, 3)
+	cam.SetPosition(float32(scn.Camera.Position[0]), float32(scn.Camera.Position[1]), float32(scn.Camera.Position[2]))
 	scene.Add(cam)
 
 	// Set up orbit control for the camera
@@ -51,16 +70,9 @@ func main() {
 	a.Subscribe(window.OnWindowSize, onResize)
 	onResize("", nil)
 
-	// axis length
-	//TODO function call to set maxs
-	xLength := float64(15)
-	zLength := float64(15)
-	yLength := float64(15)
-
-	//if max is 10 min is -5
-
-	createGraph(scene, xLength, zLength, yLength)
-	points := generateRandomCoords(20000, 0, xLength, 0, yLength, 0, zLength)
+	// Axes span the sampled region
+	createGraph(scene, scn.AxisLayout(), scn.Axes.Ticks)
+	points := scn.Sample(systems.WaveFunction(calculateWaveFunction), 0)
 	mats := plotPoints(scene, points)
 
 	// Create and add lights to the scene
@@ -92,7 +104,7 @@ func main() {
 		var vals []float64
 		for i := 0; i < len(points); i++ {
 			t := float64(time.Now().Unix()) - startTime
-			t = t / (10e-34)
+			t = t * scn.Solver.TimeScale
 			val := calculateWaveFunction(points[i][0], points[i][1], points[i][2], t)
 			vals = append(vals, imag(val)) // use real part instead of absolute value
 		}
@@ -111,10 +123,10 @@ func main() {
 
 func calculateWaveFunction(x, y, z, t float64) complex128 {
 	// Constants for the infinite square well problem
-	a := 15.0                   // width of the well
-	nx, ny, nz := 3.0, 3.0, 3.0 // quantum numbers for each dimension
-	hbar := 1.0545718e-34       // reduced Planck's constant
-	m := 9.10938356e-31         // mass of the particle
+	a := scn.System.Width[0]      // width of the well
+	n := scn.System.States()[0].N // quantum numbers for each dimension
+	nx, ny, nz := float64(n[0]), float64(n[1]), float64(n[2])
+	hbar, m := scn.System.Constants() // reduced Planck's constant and mass of the particle
 
 	// Calculate the real part of the wave function for each dimension
 	realPartX := math.Sqrt(2/a) * math.Sin(nx*math.Pi*x/a)
@@ -130,25 +142,27 @@ func calculateWaveFunction(x, y, z, t float64) complex128 {
 	return waveFunction
 }
 
-func createGraph(scene *core.Node, xLength, zLength, yLength float64) {
-	// x axis
-	geomX := geometry.NewBox(float32(xLength), 0.05, 0.05)
-	matX := material.NewStandard(math32.NewColor("DarkBlue"))
-	meshX := graphic.NewMesh(geomX, matX)
-	meshX.SetPosition(float32(xLength/2), 0, 0)
-	scene.Add(meshX)
-	// z axis
-	geomZ := geometry.NewBox(0.05, float32(zLength), 0.05)
-	matZ := material.NewStandard(math32.NewColor("DarkBlue"))
-	meshZ := graphic.NewMesh(geomZ, matZ)
-	meshZ.SetPosition(0, float32(zLength/2), 0)
-	scene.Add(meshZ)
-	// y axis
-	geomY := geometry.NewBox(0.05, 0.05, float32(yLength))
-	matY := material.NewStandard(math32.NewColor("DarkBlue"))
-	meshY := graphic.NewMesh(geomY, matY)
-	meshY.SetPosition(0, 0, float32(yLength/2))
-	scene.Add(meshY)
+// defaultScenario is the 3D infinite square well this viewer shows when no
+// scenario file is given
+func defaultScenario() *scenario.Scenario {
+	return &scenario.Scenario{
+		Name: "time dependent square well",
+		System: scenario.System{
+			Kind:    "infinite-well",
+			Width:   []float64{15, 15, 15}, // width of the well
+			Quantum: []int{3, 3, 3},        // quantum numbers for each dimension
+			Units:   "si",
+		},
+		Solver:   scenario.Solver{TimeScale: 1 / (10e-34)},
+		Sampling: scenario.Sampling{Strategy: "random", Points: 20000},
+	}
+}
+
+// createGraph draws labelled axes for the physical x, y and z ranges, with z up the screen
+func createGraph(scene *core.Node, layout [3]axes.Axis, ticks int) {
+	cfg := axes3d.DefaultConfig([3]axes.Axis{layout[0], layout[2], layout[1]})
+	cfg.Ticks = ticks
+	scene.Add(axes3d.New(cfg))
 }
 
 func plotPoints(scene *core.Node, points [][]float64) []*material.Standard {
@@ -165,36 +179,9 @@ func plotPoints(scene *core.Node, points [][]float64) []*material.Standard {
 	return Array
 }
 
-// generateRandomCoords generates random arrays of float64 within specified bounds
-func generateRandomCoords(numCoords int, minX, maxX, minY, maxY, minZ, maxZ float64) [][]float64 {
-	// Create the 2D array to store the random arrays
-	result := make([][]float64, numCoords)
-	r := rand.New(rand.NewSource(38))
-
-	// Generate random values for each array
-	for i := 0; i < numCoords; i++ {
-		result[i] = make([]float64, 3)
-		result[i][0] = minX + r.Float64()*(maxX-minX) // Random value for x
-		result[i][1] = minY + r.Float64()*(maxY-minY) // Random value for y
-		result[i][2] = minZ + r.Float64()*(maxZ-minZ) // Random value for z
-	}
-	return result
-}
-
-// GenerateColorOnGradient generates a color on a gradient from red to blue based on the input value (0 to 1)
+// GenerateColorOnGradient generates a color on the scenario's colormap based on the input value (0 to 1)
 func GenerateColorOnGradient(value float64) *math32.Color {
-	// Ensure the value is within the range of 0 to 1
-	if value < 0 {
-		value = 0
-	} else if value > 1 {
-		value = 1
-	}
-
-	// Interpolate between red and blue based on the input value
-	red := 1 - float32(value)
-	blue := float32(value)
-	green := float32(0)
-
+	red, green, blue := cmap(value)
 	return &math32.Color{R: red, G: green, B: blue}
 }
 func NormalizeVals(vals []float64) []float64 {
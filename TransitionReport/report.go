@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"image/png"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"hackathon/perturbation"
+	"hackathon/scenario"
+	"hackathon/stripchart"
+)
+
+// TransitionReport drives a scenario's system with its time-dependent
+// perturbation, follows the population of every level coupled, and sets
+// the level the drive is closest to resonance with against first-order
+// perturbation theory, Rabi oscillation and Fermi's golden rule. It writes
+// the populations as CSV with a PNG plot alongside.
+func main() {
+	out := flag.String("out", "transitions", "directory to write results to")
+	end := flag.Float64("t", 0, "time to follow the populations up to, chosen from the drive when zero")
+	samples := flag.Int("samples", 600, "times the populations are written at")
+	width := flag.Int("width", 1200, "width of the plot in pixels")
+	height := flag.Int("height", 900, "height of the plot in pixels")
+	scn := scenario.FromFlags(defaultScenario())
+	if scn.System.Drive.Kind == "none" {
+		log.Fatalf("scenario %q has no drive", scn.Name)
+	}
+	sys, err := scn.Build()
+	if err != nil {
+		log.Fatal(err)
+	}
+	e := sys.(*perturbation.Evolution)
+
+	from := 0
+	for i, n := range e.Levels {
+		if n == scn.System.States()[0].N {
+			from = i
+		}
+	}
+	dims := len(scn.System.Width)
+	fmt.Printf("%s: %s drive from level %v, time step %.4g\n", scn.Name, e.Drive.Kind, e.Levels[from][:dims], e.Step)
+	for i, n := range e.Levels {
+		fmt.Printf("  level %-10v E = %-10.6g W to start = %.6g\n", fmt.Sprint(n[:dims]), e.Energies[i], e.Coupling[i][from])
+	}
+	to, detuning := e.Resonant(from)
+	if to >= 0 {
+		fmt.Printf("closest to resonance: level %v, detuning %.4g, Rabi frequency %.4g, golden rule rate %.4g, Heisenberg time %.4g\n",
+			e.Levels[to][:dims], detuning, e.RabiFrequency(from, to), e.GoldenRule(from, to), e.Heisenberg(to))
+	}
+	if *end <= 0 {
+		*end = duration(e, from, to)
+	}
+
+	rows := follow(e, from, to, *end, *samples)
+	last := rows[len(rows)-1]
+	if to >= 0 {
+		fmt.Printf("at t = %.4g level %v holds %.6f, first order %.6f, Rabi %.6f, golden rule %.6f\n",
+			last.t, e.Levels[to][:dims], last.exact[to], last.first[to], last.rabi, last.golden)
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	write := func(name string, err error) {
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("wrote %s", filepath.Join(*out, name))
+	}
+	write("populations.csv", writePopulations(filepath.Join(*out, "populations.csv"), e, rows, dims))
+	opts := stripchart.Options{Width: *width, Height: *height}
+	write("populations.png", writePNG(filepath.Join(*out, "populations.png"), panes(e, rows, to, dims), opts))
+}
+
+// defaultScenario is the 1D well followed when no scenario file is given,
+// driven weakly at the Bohr frequency of its two lowest levels so the
+// population swings between them.
+func defaultScenario() *scenario.Scenario {
+	const width = 10
+	omega := 3 * math.Pi * math.Pi / (2 * width * width)
+	return &scenario.Scenario{
+		Name: "driven well",
+		System: scenario.System{
+			Kind:  "infinite-well",
+			Width: []float64{width},
+			Drive: scenario.Drive{Kind: "harmonic", Strength: 0.01, Omega: omega},
+		},
+	}
+}
+
+// duration picks how long to follow the populations: two Rabi cycles of
+// a harmonic drive, until four widths past the peak of a pulse, and four
+// periods of the transition for a step.
+func duration(e *perturbation.Evolution, from, to int) float64 {
+	d := e.Drive
+	switch {
+	case d.Kind == "pulse":
+		return d.Start + 4*d.Duration
+	case to < 0:
+		return d.Start + 100*e.Step
+	case d.Kind == "harmonic":
+		bohr := math.Abs(e.Energies[to]-e.Energies[from]) / e.Hbar
+		return d.Start + 2*2*math.Pi/math.Hypot(e.RabiFrequency(from, to), d.Omega-bohr)
+	}
+	return d.Start + 4*2*math.Pi*e.Hbar/math.Abs(e.Energies[to]-e.Energies[from])
+}
+
+// row is the populations at one time, with the Rabi and golden rule
+// predictions for the level closest to resonance.
+type row struct {
+	t            float64
+	exact, first []float64
+	rabi, golden float64
+}
+
+// follow evolves the populations forwards to end, sampling them n times.
+func follow(e *perturbation.Evolution, from, to int, end float64, n int) []row {
+	var rows []row
+	for i := 0; i <= n; i++ {
+		t := end * float64(i) / float64(max(n, 1))
+		r := row{t: t}
+		r.exact, r.first = e.Populations(t)
+		if to >= 0 {
+			r.rabi = e.Rabi(from, to, t)
+			r.golden = e.GoldenRule(from, to) * e.Drive.Exposure(t)
+		}
+		rows = append(rows, r)
+	}
+	return rows
+}
+
+func writePopulations(path string, e *perturbation.Evolution, rows []row, dims int) error {
+	header := []string{"Time"}
+	for _, n := range e.Levels {
+		header = append(header, fmt.Sprint(n[:dims]), fmt.Sprintf("%v first order", n[:dims]))
+	}
+	header = append(header, "Rabi", "Golden rule")
+	records := [][]string{header}
+	for _, r := range rows {
+		record := []string{num(r.t)}
+		for i := range e.Levels {
+			record = append(record, num(r.exact[i]), num(r.first[i]))
+		}
+		records = append(records, append(record, num(r.rabi), num(r.golden)))
+	}
+	return writeCSV(path, records)
+}
+
+func writeCSV(path string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(file)
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func num(v float64) string {
+	return strconv.FormatFloat(v, 'g', 8, 64)
+}
+
+// panes plots the population of every level, then the level closest to
+// resonance against the predictions of theory. Those grow without bound
+// once perturbation theory fails, so they are cut off at one.
+func panes(e *perturbation.Evolution, rows []row, to, dims int) []stripchart.Pane {
+	all := stripchart.Pane{Title: "population of each level", XLabel: "t"}
+	for i, n := range e.Levels {
+		tr := stripchart.Trace{Label: fmt.Sprint(n[:dims]), Color: i}
+		for _, r := range rows {
+			tr.X, tr.Y = append(tr.X, r.t), append(tr.Y, r.exact[i])
+		}
+		all.Traces = append(all.Traces, tr)
+	}
+	if to < 0 {
+		return []stripchart.Pane{all}
+	}
+
+	target := stripchart.Pane{Title: fmt.Sprintf("level %v", e.Levels[to][:dims]), XLabel: "t"}
+	line := func(label string, color int, get func(row) float64) stripchart.Trace {
+		tr := stripchart.Trace{Label: label, Color: color}
+		for _, r := range rows {
+			tr.X, tr.Y = append(tr.X, r.t), append(tr.Y, math.Min(get(r), 1))
+		}
+		return tr
+	}
+	target.Traces = append(target.Traces,
+		line("exact", 1, func(r row) float64 { return r.exact[to] }),
+		line("first order", 0, func(r row) float64 { return r.first[to] }))
+	if e.Drive.Kind == "harmonic" {
+		target.Traces = append(target.Traces, line("Rabi", 2, func(r row) float64 { return r.rabi }))
+	}
+	target.Traces = append(target.Traces, line("golden rule", 3, func(r row) float64 { return r.golden }))
+	return []stripchart.Pane{all, target}
+}
+
+func writePNG(path string, panes []stripchart.Pane, opts stripchart.Options) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, stripchart.Plot(panes, opts)); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
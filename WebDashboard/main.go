@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"hackathon/dashboard"
+	"hackathon/scenario"
+	"voltmeter/acquire"
+)
+
+// WebDashboard serves a web page on the lab network showing the voltmeter's
+// readings and the scenario's wavefunction as it evolves.
+func main() {
+	settings := acquire.RegisterFlags(flag.CommandLine)
+	addr := flag.String("addr", ":8080", "address to serve the dashboard on")
+	voltmeter := flag.Bool("voltmeter", true, "stream readings from the voltmeter, or the recording given by -replay")
+	fps := flag.Float64("fps", 10, "wavefunction frames per second")
+	mode := flag.String("frames", "grid", "wavefunction frames: grid for a slice through the box, points for the scenario's sample points")
+	size := flag.Int("grid", 96, "grid frames are this many values along each side")
+	scn := scenario.FromFlags(defaultScenario())
+	if *mode != "grid" && *mode != "points" {
+		log.Fatalf("unknown -frames %q, want grid or points", *mode)
+	}
+	sys, err := scn.Build()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server := dashboard.New()
+	info := dashboard.Info{Title: scn.Name, Source: "no voltmeter"}
+	if *voltmeter {
+		config, cal, err := settings.Load()
+		if err != nil {
+			log.Fatal(err)
+		}
+		readings, err := settings.Open(config, cal)
+		if err != nil {
+			log.Fatal(err)
+		}
+		server.Name = cal.ChannelName
+		info.Source = settings.Source(config)
+		go func() {
+			for r := range readings {
+				server.PublishReading(r)
+			}
+			log.Print("readings finished")
+		}()
+	}
+	server.SetInfo(info)
+
+	// Frames follow the wall clock scaled by the scenario, like the viewers
+	go func() {
+		points := scn.Sample(sys, 0)
+		start := time.Now()
+		tick := time.NewTicker(time.Duration(float64(time.Second) / *fps))
+		defer tick.Stop()
+		for range tick.C {
+			if !server.WatchingFrames() {
+				continue
+			}
+			t := time.Since(start).Seconds() * scn.Solver.TimeScale
+			if *mode == "points" {
+				server.PublishFrame(dashboard.PointFrame(scn, sys, points, t))
+			} else {
+				server.PublishFrame(dashboard.GridFrame(scn, sys, t, *size, *size))
+			}
+		}
+	}()
+
+	log.Printf("serving %s on http://%s/", scn.Name, *addr)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
+
+// defaultScenario is the 2D superposition shown when no scenario file is
+// given, which moves enough to show the stream is live
+func defaultScenario() *scenario.Scenario {
+	return &scenario.Scenario{
+		Name: "superposition in a square well",
+		System: scenario.System{
+			Kind:  "infinite-well",
+			Width: []float64{10, 10},
+			Initial: []scenario.Component{
+				{Quantum: []int{1, 1}, Re: 1},
+				{Quantum: []int{2, 1}, Re: 1},
+			},
+		},
+		Solver:   scenario.Solver{TimeScale: 0.2},
+		Sampling: scenario.Sampling{Strategy: "random", Points: 4000},
+		Display:  "real",
+	}
+}
@@ -0,0 +1,56 @@
+package analysis
+
+import "math"
+
+// Correlation is the normalised cross-correlation of two evenly sampled
+// signals at a range of lags.
+type Correlation struct {
+	Lag []float64 // seconds b is shifted by
+	R   []float64 // Pearson coefficient at each lag, from -1 to 1
+}
+
+// CrossCorrelate compares a and b, sampled every dt seconds over the same
+// span, at lags up to maxLag either way. A positive lag means b follows a:
+// b(t+lag) is compared against a(t).
+func CrossCorrelate(a, b []float64, dt, maxLag float64) Correlation {
+	n := min(len(a), len(b))
+	steps := int(math.Round(maxLag / dt))
+	steps = min(steps, n-2)
+	var c Correlation
+	for k := -steps; k <= steps; k++ {
+		lo, hi := max(0, -k), min(n, n-k)
+		c.Lag = append(c.Lag, float64(k)*dt)
+		c.R = append(c.R, pearson(a[lo:hi], b[lo+k:hi+k]))
+	}
+	return c
+}
+
+// Best returns the lag at which b most resembles a, the delay from a to b
+// when one drives the other. Periodic signals match again every period, so
+// the shortest of equally good lags wins.
+func (c Correlation) Best() (lag, r float64) {
+	r = math.Inf(-1)
+	for i := range c.R {
+		if c.R[i] > r+1e-9 || (c.R[i] > r-1e-9 && math.Abs(c.Lag[i]) < math.Abs(lag)) {
+			lag, r = c.Lag[i], c.R[i]
+		}
+	}
+	return lag, r
+}
+
+// pearson returns the correlation coefficient of two equal length slices,
+// zero when either is constant.
+func pearson(a, b []float64) float64 {
+	ma, mb := Mean(a), Mean(b)
+	var sab, saa, sbb float64
+	for i := range a {
+		da, db := a[i]-ma, b[i]-mb
+		sab += da * db
+		saa += da * da
+		sbb += db * db
+	}
+	if saa == 0 || sbb == 0 {
+		return 0
+	}
+	return sab / math.Sqrt(saa*sbb)
+}
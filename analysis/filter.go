@@ -0,0 +1,99 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+)
+
+// MovingAverage returns the mean of the n readings centred on each reading,
+// fewer at the ends of the series. An even window holds one more reading
+// before its centre than after it.
+func MovingAverage(v []float64, n int) []float64 {
+	out := make([]float64, len(v))
+	if n < 1 {
+		copy(out, v)
+		return out
+	}
+	half := n / 2
+	sum, lo, hi := 0.0, 0, 0 // running sum of v[lo:hi]
+	for i := range v {
+		for hi < len(v) && hi < i-half+n {
+			sum += v[hi]
+			hi++
+		}
+		for lo < i-half {
+			sum -= v[lo]
+			lo++
+		}
+		out[i] = sum / float64(hi-lo)
+	}
+	return out
+}
+
+// Median returns the median of the n readings centred on each reading,
+// which removes isolated spikes without rounding off steps. Windows are
+// placed as in MovingAverage.
+func Median(v []float64, n int) []float64 {
+	out := make([]float64, len(v))
+	if n < 1 {
+		copy(out, v)
+		return out
+	}
+	half := n / 2
+	window := make([]float64, 0, n)
+	for i := range v {
+		lo, hi := max(0, i-half), min(len(v), i-half+n)
+		window = append(window[:0], v[lo:hi]...)
+		sort.Float64s(window)
+		if m := len(window); m%2 == 1 {
+			out[i] = window[m/2]
+		} else {
+			out[i] = (window[m/2-1] + window[m/2]) / 2
+		}
+	}
+	return out
+}
+
+// LowPass runs a first order RC filter with the given cutoff in Hz over
+// readings taken at times t in seconds. Uneven spacing is allowed.
+func LowPass(t, v []float64, cutoff float64) []float64 {
+	out := make([]float64, len(v))
+	if len(v) == 0 {
+		return out
+	}
+	rc := 1 / (2 * math.Pi * cutoff)
+	out[0] = v[0]
+	for i := 1; i < len(v); i++ {
+		dt := t[i] - t[i-1]
+		alpha := dt / (rc + dt)
+		out[i] = out[i-1] + alpha*(v[i]-out[i-1])
+	}
+	return out
+}
+
+// Mean returns the average of v, zero when it is empty.
+func Mean(v []float64) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, x := range v {
+		sum += x
+	}
+	return sum / float64(len(v))
+}
+
+// RMS returns the root mean square of v, and of v with its mean removed,
+// the AC part a multimeter on an AC range would show.
+func RMS(v []float64) (total, ac float64) {
+	if len(v) == 0 {
+		return 0, 0
+	}
+	mean := Mean(v)
+	for _, x := range v {
+		total += x * x
+		ac += (x - mean) * (x - mean)
+	}
+	n := float64(len(v))
+	return math.Sqrt(total / n), math.Sqrt(ac / n)
+}
@@ -0,0 +1,67 @@
+package analysis
+
+import "sort"
+
+// Peak is a local maximum of a series.
+type Peak struct {
+	Index      int
+	T, V       float64
+	Prominence float64 // height above the higher of the lowest points separating it from taller peaks
+}
+
+// Peaks finds the local maxima standing at least prominence above their
+// surroundings. Of peaks closer together than distance seconds only the
+// tallest is kept. The result is in time order.
+func Peaks(t, v []float64, prominence, distance float64) []Peak {
+	var found []Peak
+	for i := 1; i < len(v)-1; i++ {
+		if v[i] <= v[i-1] || v[i] < v[i+1] {
+			continue
+		}
+		// Skip to the far edge of a flat top so it counts once
+		j := i
+		for j < len(v)-1 && v[j+1] == v[i] {
+			j++
+		}
+		if j == len(v)-1 || v[j+1] > v[i] {
+			i = j
+			continue
+		}
+		p := Peak{Index: i, T: t[i], V: v[i], Prominence: v[i] - max(base(v, i, -1), base(v, i, 1))}
+		if p.Prominence >= prominence {
+			found = append(found, p)
+		}
+		i = j
+	}
+
+	// Keep the tallest of peaks within distance of each other
+	sort.Slice(found, func(a, b int) bool { return found[a].V > found[b].V })
+	var kept []Peak
+	for _, p := range found {
+		near := false
+		for _, k := range kept {
+			if p.T-k.T < distance && k.T-p.T < distance {
+				near = true
+				break
+			}
+		}
+		if !near {
+			kept = append(kept, p)
+		}
+	}
+	sort.Slice(kept, func(a, b int) bool { return kept[a].Index < kept[b].Index })
+	return kept
+}
+
+// base walks from peak i in direction dir until a taller reading or the end
+// of the series and returns the lowest reading passed.
+func base(v []float64, i, dir int) float64 {
+	lowest := v[i]
+	for j := i + dir; j >= 0 && j < len(v); j += dir {
+		if v[j] > v[i] {
+			break
+		}
+		lowest = min(lowest, v[j])
+	}
+	return lowest
+}
@@ -0,0 +1,102 @@
+// Package analysis processes voltmeter recordings: smoothing and filtering,
+// spectra, RMS, peak detection and correlation between channels. Every
+// function works on plain slices so results can be written out or plotted
+// however a caller likes.
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"voltmeter/acquire"
+	"voltmeter/calibration"
+)
+
+// Series is the voltage on one ADC pin over a recording. T is in seconds
+// from the first reading of the recording, on any channel.
+type Series struct {
+	Pin  int
+	Name string
+	T, V []float64
+}
+
+// Load reads every reading of the recording at path and splits them into
+// one series per channel, ordered by pin.
+func Load(path string, cal calibration.Calibration) ([]Series, error) {
+	replay := &acquire.Replay{Path: path, Cal: cal}
+	readings, err := replay.Readings()
+	if err != nil {
+		return nil, err
+	}
+	var start time.Time
+	byPin := map[int]*Series{}
+	for r := range readings {
+		if start.IsZero() {
+			start = r.Received
+		}
+		t := r.Received.Sub(start).Seconds()
+		for i, pin := range r.Channels {
+			s, ok := byPin[pin]
+			if !ok {
+				s = &Series{Pin: pin, Name: cal.ChannelName(pin)}
+				byPin[pin] = s
+			}
+			s.T = append(s.T, t)
+			s.V = append(s.V, r.Volts[i])
+		}
+	}
+	if len(byPin) == 0 {
+		return nil, fmt.Errorf("%s: no readings", path)
+	}
+
+	series := make([]Series, 0, len(byPin))
+	for _, s := range byPin {
+		series = append(series, *s)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Pin < series[j].Pin })
+	return series, nil
+}
+
+// Interval is the median time between readings, which ignores the odd gap
+// left by a reconnect. It is zero with fewer than two readings.
+func (s Series) Interval() float64 {
+	if len(s.T) < 2 {
+		return 0
+	}
+	gaps := make([]float64, len(s.T)-1)
+	for i := range gaps {
+		gaps[i] = s.T[i+1] - s.T[i]
+	}
+	sort.Float64s(gaps)
+	return gaps[len(gaps)/2]
+}
+
+// Resample interpolates the series linearly onto an even grid of step dt
+// from from to to, which the spectrum and correlation need. Times outside
+// the series take its first or last value.
+func (s Series) Resample(from, to, dt float64) []float64 {
+	if len(s.T) == 0 || dt <= 0 || to < from {
+		return nil
+	}
+	n := int(math.Floor((to-from)/dt)) + 1
+	out := make([]float64, n)
+	j := 0
+	for i := range out {
+		t := from + float64(i)*dt
+		for j < len(s.T)-1 && s.T[j+1] < t {
+			j++
+		}
+		switch {
+		case t <= s.T[0]:
+			out[i] = s.V[0]
+		case j == len(s.T)-1:
+			out[i] = s.V[j]
+		default:
+			f := (t - s.T[j]) / (s.T[j+1] - s.T[j])
+			out[i] = s.V[j] + f*(s.V[j+1]-s.V[j])
+		}
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+package analysis
+
+import (
+	"math"
+	"math/cmplx"
+
+	"gonum.org/v1/gonum/dsp/fourier"
+)
+
+// Spectrum is the single sided amplitude spectrum of an evenly sampled
+// signal.
+type Spectrum struct {
+	Freq      []float64 // Hz
+	Amplitude []float64 // volts, the amplitude of a sine at that frequency
+}
+
+// NewSpectrum transforms readings taken every dt seconds. The mean is
+// removed and a Hann window applied, so the spectrum shows the fluctuations
+// rather than the DC level and leaks less between bins.
+func NewSpectrum(v []float64, dt float64) Spectrum {
+	n := len(v)
+	if n < 2 || dt <= 0 {
+		return Spectrum{}
+	}
+	mean := Mean(v)
+	windowed := make([]float64, n)
+	gain := 0.0
+	for i, x := range v {
+		w := 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(n-1)))
+		windowed[i] = (x - mean) * w
+		gain += w
+	}
+
+	fft := fourier.NewFFT(n)
+	coeffs := fft.Coefficients(nil, windowed)
+	s := Spectrum{Freq: make([]float64, len(coeffs)), Amplitude: make([]float64, len(coeffs))}
+	for i, c := range coeffs {
+		s.Freq[i] = fft.Freq(i) / dt
+		s.Amplitude[i] = 2 * cmplx.Abs(c) / gain
+	}
+	return s
+}
+
+// Dominant returns the frequency and amplitude of the strongest component
+// above DC, zeros for an empty spectrum.
+func (s Spectrum) Dominant() (freq, amplitude float64) {
+	for i := 1; i < len(s.Freq); i++ {
+		if s.Amplitude[i] > amplitude {
+			freq, amplitude = s.Freq[i], s.Amplitude[i]
+		}
+	}
+	return freq, amplitude
+}
@@ -0,0 +1,96 @@
+// Package axes works out axis extents, tick positions and tick labels. It
+// holds no rendering code so the g3n viewers (through axes3d) and the headless
+// exporters lay out their axes the same way.
+package axes
+
+import (
+	"math"
+	"strconv"
+)
+
+// Range is the interval an axis covers. Min may be negative.
+type Range struct {
+	Min, Max float64
+}
+
+// Length returns the distance covered by the range.
+func (r Range) Length() float64 {
+	return r.Max - r.Min
+}
+
+// Contains reports whether v lies inside the range.
+func (r Range) Contains(v float64) bool {
+	return v >= r.Min && v <= r.Max
+}
+
+// Axis describes one physically labelled axis.
+type Axis struct {
+	Range Range
+	Label string // quantity along the axis, e.g. "x"
+	Unit  string // unit of the quantity, e.g. "m", empty for dimensionless
+}
+
+// Title returns the label with its unit, e.g. "x (m)".
+func (a Axis) Title() string {
+	if a.Unit == "" {
+		return a.Label
+	}
+	return a.Label + " (" + a.Unit + ")"
+}
+
+// FromBounds derives one range per axis from the corners of a simulation
+// domain. A degenerate axis, as used by the 2D systems, gets a unit range so
+// it is still drawn.
+func FromBounds(min, max [3]float64) [3]Range {
+	var ranges [3]Range
+	for i := range ranges {
+		ranges[i] = Range{Min: min[i], Max: max[i]}
+		if ranges[i].Length() == 0 {
+			ranges[i].Max = ranges[i].Min + 1
+		}
+	}
+	return ranges
+}
+
+// Ticks returns evenly spaced "nice" tick positions (multiples of 1, 2 or 5
+// times a power of ten) inside r, aiming for roughly target ticks.
+func Ticks(r Range, target int) []float64 {
+	if target < 2 || r.Length() <= 0 {
+		return []float64{r.Min, r.Max}
+	}
+	step := niceStep(r.Length() / float64(target-1))
+	first := math.Ceil(r.Min/step) * step
+	var ticks []float64
+	for v := first; v <= r.Max+step*1e-9; v += step {
+		// Snap values that accumulate rounding error onto the step grid
+		ticks = append(ticks, math.Round(v/step)*step)
+	}
+	return ticks
+}
+
+func niceStep(raw float64) float64 {
+	exp := math.Floor(math.Log10(raw))
+	base := math.Pow(10, exp)
+	switch f := raw / base; {
+	case f <= 1:
+		return base
+	case f <= 2:
+		return 2 * base
+	case f <= 5:
+		return 5 * base
+	}
+	return 10 * base
+}
+
+// Format renders a tick value compactly, switching to exponent notation for
+// very large or small magnitudes such as SI lengths.
+func Format(v float64) string {
+	if v == 0 {
+		return "0"
+	}
+	abs := math.Abs(v)
+	if abs >= 1e5 || abs < 1e-3 {
+		return strconv.FormatFloat(v, 'e', 2, 64)
+	}
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
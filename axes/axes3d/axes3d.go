@@ -0,0 +1,111 @@
+// Package axes3d draws labelled axes into a g3n scene. Tick labels are
+// rendered with the FreeSans font bundled with g3n, through its freetype based
+// text package, onto sprites that always face the camera.
+package axes3d
+
+import (
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/geometry"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/gui/assets"
+	"github.com/g3n/engine/material"
+	"github.com/g3n/engine/math32"
+	"github.com/g3n/engine/text"
+	"github.com/g3n/engine/texture"
+
+	"hackathon/axes"
+)
+
+// Config describes the axes to draw. Axes are given in scene order: X to the
+// right, Y up and Z towards the camera.
+type Config struct {
+	Axes      [3]axes.Axis
+	Ticks     int           // approximate number of ticks per axis
+	Color     *math32.Color // colour of the axis bars and ticks
+	Thickness float32       // width of the axis bars
+	TextSize  float32       // height of the tick labels in scene units
+}
+
+// DefaultConfig returns dark blue axes of the thickness the viewers have
+// always drawn.
+func DefaultConfig(a [3]axes.Axis) Config {
+	return Config{
+		Axes:      a,
+		Ticks:     5,
+		Color:     math32.NewColor("DarkBlue"),
+		Thickness: 0.05,
+		TextSize:  0.4,
+	}
+}
+
+// New builds the axes described by cfg and returns a node holding them.
+func New(cfg Config) *core.Node {
+	node := core.NewNode()
+	font := newFont()
+	mat := material.NewStandard(cfg.Color)
+
+	for i, a := range cfg.Axes {
+		// The bar spans the whole range, so negative ranges extend past the origin
+		var size, center math32.Vector3
+		size.SetComponent(i, float32(a.Range.Length()))
+		size.SetComponent((i+1)%3, cfg.Thickness)
+		size.SetComponent((i+2)%3, cfg.Thickness)
+		center.SetComponent(i, float32((a.Range.Min+a.Range.Max)/2))
+		bar := graphic.NewMesh(geometry.NewBox(size.X, size.Y, size.Z), mat)
+		bar.SetPositionVec(&center)
+		node.Add(bar)
+
+		// Ticks stick out along the next axis, labels sit just beyond them
+		out := (i + 1) % 3
+		if i == 0 {
+			out = 2
+		}
+		tickLength := 4 * cfg.Thickness
+		for _, v := range axes.Ticks(a.Range, cfg.Ticks) {
+			var tickSize, pos math32.Vector3
+			tickSize.SetComponent(i, cfg.Thickness)
+			tickSize.SetComponent(out, tickLength)
+			tickSize.SetComponent(3-i-out, cfg.Thickness)
+			pos.SetComponent(i, float32(v))
+			tick := graphic.NewMesh(geometry.NewBox(tickSize.X, tickSize.Y, tickSize.Z), mat)
+			tick.SetPositionVec(&pos)
+			node.Add(tick)
+
+			label := newLabel(font, axes.Format(v), cfg.TextSize)
+			pos.SetComponent(out, tickLength+cfg.TextSize)
+			label.SetPositionVec(&pos)
+			node.Add(label)
+		}
+
+		// Axis title past the positive end
+		title := newLabel(font, a.Title(), 1.5*cfg.TextSize)
+		var pos math32.Vector3
+		pos.SetComponent(i, float32(a.Range.Max)+2*cfg.TextSize)
+		title.SetPositionVec(&pos)
+		node.Add(title)
+	}
+	return node
+}
+
+// newFont loads the font the tick labels are drawn with. A large point size
+// keeps the text sharp when the sprites are scaled up.
+func newFont() *text.Font {
+	font, err := text.NewFontFromData(assets.MustAsset("fonts/FreeSans.ttf"))
+	if err != nil {
+		panic(err)
+	}
+	font.SetPointSize(48)
+	font.SetColor(&math32.Color4{R: 1, G: 1, B: 1, A: 1})
+	return font
+}
+
+// newLabel renders s onto a camera facing sprite of the given height.
+func newLabel(font *text.Font, s string, height float32) *graphic.Sprite {
+	img := font.DrawText(s)
+	tex := texture.NewTexture2DFromRGBA(img)
+	mat := material.NewStandard(&math32.Color{R: 1, G: 1, B: 1})
+	mat.AddTexture(tex)
+	mat.SetTransparent(true)
+	width := height * float32(img.Bounds().Dx()) / float32(img.Bounds().Dy())
+	return graphic.NewSprite(width, height, mat)
+}
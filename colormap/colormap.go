@@ -0,0 +1,76 @@
+// Package colormap maps normalised values onto colours. It is shared by the
+// g3n viewers and the headless exporters so both draw the same picture.
+package colormap
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Map turns a value between 0 and 1 into a colour. Values outside the range
+// are clamped.
+type Map func(value float64) (r, g, b float32)
+
+var maps = map[string]Map{
+	"red-blue":   RedBlue,
+	"green-blue": GreenBlue,
+	"greyscale":  Greyscale,
+	"heat":       Heat,
+}
+
+// Lookup returns the colormap with the given name. An empty name selects
+// red-blue, the gradient the viewers have always used.
+func Lookup(name string) (Map, error) {
+	if name == "" {
+		return RedBlue, nil
+	}
+	m, ok := maps[name]
+	if !ok {
+		return nil, fmt.Errorf("colormap: unknown colormap %q (have %v)", name, Names())
+	}
+	return m, nil
+}
+
+// Names lists the available colormaps in alphabetical order.
+func Names() []string {
+	var names []string
+	for name := range maps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func clamp(value float64) float32 {
+	if value < 0 {
+		return 0
+	} else if value > 1 {
+		return 1
+	}
+	return float32(value)
+}
+
+// RedBlue interpolates from red at 0 to blue at 1.
+func RedBlue(value float64) (r, g, b float32) {
+	v := clamp(value)
+	return 1 - v, 0, v
+}
+
+// GreenBlue fades from green towards blue, as used by the NoBoundaryConditions viewer.
+func GreenBlue(value float64) (r, g, b float32) {
+	v := clamp(value)
+	return 0, 0.7 - v/2, v
+}
+
+// Greyscale runs from black at 0 to white at 1.
+func Greyscale(value float64) (r, g, b float32) {
+	v := clamp(value)
+	return v, v, v
+}
+
+// Heat runs black, red, yellow, white.
+func Heat(value float64) (r, g, b float32) {
+	v := clamp(value) * 3
+	r, g, b = v, v-1, v-2
+	return clamp(float64(r)), clamp(float64(g)), clamp(float64(b))
+}
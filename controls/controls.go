@@ -0,0 +1,234 @@
+// Package controls provides an in-window g3n panel for editing a scenario
+// while a viewer runs. The panel writes straight into the scenario and
+// records what changed; viewers poll it once per frame and rebuild only what
+// they need to, so dragging a slider does not rebuild the scene per event.
+package controls
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+
+	"hackathon/colormap"
+	"hackathon/scenario"
+	"hackathon/systems"
+)
+
+// Change records which parts of a viewer are out of date.
+type Change int
+
+const (
+	Recolor  Change = 1 << iota // colours need recomputing from the existing points
+	Rebuild                     // the system must be rebuilt from the scenario
+	Resample                    // the sample points and axes must be regenerated
+)
+
+// Has reports whether c includes all of the changes in other.
+func (c Change) Has(other Change) bool {
+	return c&other == other
+}
+
+const (
+	panelWidth  = 240
+	sliderWidth = 220
+	rowHeight   = 24
+)
+
+// Panel is a column of sliders and dropdowns bound to a scenario.
+type Panel struct {
+	*gui.Panel
+	scn     *scenario.Scenario
+	pending Change
+}
+
+// New builds a panel editing scn: sliders for the quantum numbers, well width,
+// perturbation strength v_0, the strength and frequency of any drive, time
+// scale and point count, and dropdowns for
+// the display mode and colormap. Slider ranges are chosen around the
+// scenario's starting values.
+func New(scn *scenario.Scenario) *Panel {
+	p := &Panel{Panel: gui.NewPanel(panelWidth, 0), scn: scn}
+	p.SetColor4(&math32.Color4{R: 0.2, G: 0.2, B: 0.2, A: 0.8})
+	p.SetPaddings(6, 6, 6, 6)
+	layout := gui.NewVBoxLayout()
+	layout.SetSpacing(4)
+	p.SetLayout(layout)
+
+	axisNames := []string{"n_x", "n_y", "n_z"}
+	ground := float64(scn.System.Ground())
+	ranges := [][2]float64{{ground, ground + 9}, {ground, ground + 9}, {ground, ground + 9}}
+	if scn.System.Kind == "hydrogen" {
+		// An orbital is picked by n, l and m; values no orbital has are
+		// refused when the atom is rebuilt
+		axisNames = []string{"n", "l", "m"}
+		ranges = [][2]float64{{1, 10}, {0, 9}, {-9, 9}}
+	}
+	dims := len(scn.System.Width)
+	if scn.System.Kind == "scattering" {
+		// A scattering state is set by its barrier and energy, not quantum numbers
+		dims = 0
+	}
+	for i := 0; i < dims; i++ {
+		i := i
+		p.addSlider(axisNames[i], ranges[i][0], ranges[i][1], float64(scn.System.States()[0].N[i]), true, func(v float64) {
+			// Picking a quantum number collapses any superposition or
+			// Gaussian state to one eigenstate
+			n := scn.System.States()[0].N
+			n[i] = int(v)
+			scn.System.Initial = nil
+			scn.System.Coherent, scn.System.Squeeze = nil, nil
+			scn.System.Quantum = n[:len(scn.System.Width)]
+			p.pending |= affected(scn, "quantum")
+		})
+	}
+
+	width := scn.System.Width[0]
+	p.addSlider("well width", 1, 4*width, width, false, func(v float64) {
+		scn.SetWidth(v)
+		p.pending |= affected(scn, "width")
+	})
+
+	if scn.System.Kind == "scattering" {
+		energy := scn.System.Incident.Energy
+		p.addSlider("energy", energy/10, 4*energy, energy, false, func(v float64) {
+			scn.System.Incident.Energy = v
+			p.pending |= affected(scn, "energy")
+		})
+	}
+
+	v0 := scn.V0()
+	p.addSlider("v_0", 0, upper(v0), v0, false, func(v float64) {
+		scn.SetV0(v)
+		p.pending |= affected(scn, "v0")
+	})
+
+	if d := scn.System.Drive; d.Kind != "none" {
+		p.addSlider("drive", 0, upper(d.Strength), d.Strength, false, func(v float64) {
+			scn.System.Drive.Strength = v
+			p.pending |= affected(scn, "drive")
+		})
+		if d.Kind != "step" {
+			p.addSlider("drive ω", 0, math.Max(2*d.Omega, 1), d.Omega, false, func(v float64) {
+				scn.System.Drive.Omega = v
+				p.pending |= affected(scn, "drive-omega")
+			})
+		}
+	}
+
+	timeScale := scn.Solver.TimeScale
+	p.addSlider("time scale", 0, upper(timeScale), timeScale, false, func(v float64) {
+		scn.SetTimeScale(v)
+	})
+
+	p.addSlider("points", 1000, 50000, float64(scn.Sampling.Points), true, func(v float64) {
+		scn.Sampling.Points = int(v)
+		p.pending |= Resample | Recolor
+	})
+
+	p.addDropDown("display", systems.Quantities, scn.Display, func(v string) {
+		scn.Display = v
+		p.pending |= Recolor
+	})
+	p.addDropDown("colormap", colormap.Names(), scn.Colormap, func(v string) {
+		scn.Colormap = v
+		p.pending |= Recolor
+	})
+
+	p.SetHeight(p.contentHeight())
+	p.SetPosition(10, 10)
+	return p
+}
+
+// Poll returns the changes made since the last call and clears them.
+func (p *Panel) Poll() Change {
+	c := p.pending
+	p.pending = 0
+	return c
+}
+
+// affected returns what a viewer must redo after a parameter of scn
+// changes. Changing the width moves the sampled box; anything else that
+// alters the state only needs a rebuild, except that density sampled points
+// follow |ψ|² and are redrawn as well. The time scale is read every frame.
+func affected(scn *scenario.Scenario, parameter string) Change {
+	switch parameter {
+	case "time-scale":
+		return 0
+	case "width":
+		return Rebuild | Resample | Recolor
+	}
+	c := Rebuild | Recolor
+	if scn.Sampling.Strategy == "density" {
+		c |= Resample
+	}
+	return c
+}
+
+// upper picks the top of a slider's range from its starting value.
+func upper(v float64) float64 {
+	if v == 0 {
+		return 1
+	}
+	return 10 * math.Abs(v)
+}
+
+// addSlider adds a labelled slider mapping its position onto [min, max].
+// Integer sliders snap to whole numbers.
+func (p *Panel) addSlider(name string, min, max, value float64, integer bool, set func(float64)) {
+	label := gui.NewLabel("")
+	slider := gui.NewHSlider(sliderWidth, rowHeight)
+	slider.SetValue(float32((value - min) / (max - min)))
+
+	show := func(v float64) {
+		if integer {
+			label.SetText(fmt.Sprintf("%s: %d", name, int(v)))
+		} else {
+			label.SetText(fmt.Sprintf("%s: %.4g", name, v))
+		}
+	}
+	show(value)
+
+	last := value
+	slider.Subscribe(gui.OnChange, func(evname string, ev interface{}) {
+		v := min + float64(slider.Value())*(max-min)
+		if integer {
+			v = math.Round(v)
+		}
+		if v == last {
+			return
+		}
+		last = v
+		show(v)
+		set(v)
+	})
+	p.Add(label)
+	p.Add(slider)
+}
+
+// addDropDown adds a labelled dropdown of options with current selected.
+func (p *Panel) addDropDown(name string, options []string, current string, set func(string)) {
+	p.Add(gui.NewLabel(name))
+	dd := gui.NewDropDown(sliderWidth, gui.NewImageLabel(current))
+	for _, o := range options {
+		item := gui.NewImageLabel(o)
+		dd.Add(item)
+		if o == current {
+			dd.SetSelected(item)
+		}
+	}
+	dd.Subscribe(gui.OnChange, func(evname string, ev interface{}) {
+		set(dd.Selected().Text())
+	})
+	p.Add(dd)
+}
+
+// contentHeight sums the heights of the panel's rows so it fits them exactly.
+func (p *Panel) contentHeight() float32 {
+	h := float32(12)
+	for _, child := range p.Children() {
+		h += child.(gui.IPanel).GetPanel().Height() + 4
+	}
+	return h
+}
@@ -0,0 +1,118 @@
+package controls
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"hackathon/scenario"
+	"voltmeter/acquire"
+)
+
+// deadband is the fraction of an input's range it must move before the
+// parameter is updated, so ADC noise does not rebuild the scene every frame.
+const deadband = 0.002
+
+// Knobs steers a scenario from voltmeter readings, following the inputs the
+// scenario lists. Like Panel it writes into the scenario and is polled once
+// per frame.
+type Knobs struct {
+	scn      *scenario.Scenario
+	readings <-chan acquire.Reading
+	inputs   []knob
+	pending  Change
+}
+
+// knob is the smoothing state of one input.
+type knob struct {
+	scenario.Input
+	value   float64   // smoothed parameter value
+	applied float64   // value last written to the scenario
+	at      time.Time // time of the last reading, zero before the first
+}
+
+// NewKnobs follows the scenario's inputs using readings. With no inputs it
+// never reads and Poll always returns no change.
+func NewKnobs(scn *scenario.Scenario, readings <-chan acquire.Reading) *Knobs {
+	k := &Knobs{scn: scn, readings: readings}
+	for _, in := range scn.Inputs {
+		k.inputs = append(k.inputs, knob{Input: in})
+	}
+	return k
+}
+
+// KnobsFromFlags opens the voltmeter, or the recording, selected by the
+// command line when the scenario has inputs. Call it after
+// acquire.RegisterFlags and scenario.FromFlags. It exits the program if the
+// source cannot be opened.
+func KnobsFromFlags(scn *scenario.Scenario, settings *acquire.Flags) *Knobs {
+	if len(scn.Inputs) == 0 {
+		return NewKnobs(scn, nil)
+	}
+	config, cal, err := settings.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	readings, err := settings.Open(config, cal)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return NewKnobs(scn, readings)
+}
+
+// Poll applies every reading that arrived since the last call and returns
+// the changes made.
+func (k *Knobs) Poll() Change {
+drain:
+	for k.readings != nil {
+		select {
+		case r, ok := <-k.readings:
+			if !ok {
+				k.readings = nil
+				break drain
+			}
+			k.read(r)
+		default:
+			break drain
+		}
+	}
+	c := k.pending
+	k.pending = 0
+	return c
+}
+
+// read maps a reading onto the inputs bound to its channels.
+func (k *Knobs) read(r acquire.Reading) {
+	for i := range k.inputs {
+		in := &k.inputs[i]
+		volts, ok := r.Volt(in.Channel)
+		if !ok {
+			continue
+		}
+
+		// Linear map from the voltage span onto the parameter range, clamped at the ends
+		f := (volts - in.Volts[0]) / (in.Volts[1] - in.Volts[0])
+		f = math.Max(0, math.Min(1, f))
+		target := in.Range[0] + f*(in.Range[1]-in.Range[0])
+
+		// Exponential smoothing with the input's time constant
+		if in.at.IsZero() || in.Smoothing == 0 {
+			in.value = target
+		} else {
+			dt := r.Received.Sub(in.at).Seconds()
+			in.value += (target - in.value) * (1 - math.Exp(-dt/in.Smoothing))
+		}
+		first := in.at.IsZero()
+		in.at = r.Received
+
+		if !first && math.Abs(in.value-in.applied) < deadband*math.Abs(in.Range[1]-in.Range[0]) {
+			continue
+		}
+		if err := k.scn.SetParameter(in.Parameter, in.value); err != nil {
+			log.Printf("input on ADC%d: %v", in.Channel, err)
+			continue
+		}
+		in.applied = in.value
+		k.pending |= affected(k.scn, in.Parameter)
+	}
+}
@@ -0,0 +1,93 @@
+package dashboard
+
+import (
+	"log"
+	"sync"
+)
+
+// queueLength is how many messages a browser may fall behind by before it
+// starts missing them. A slow client must never hold up the serial reader.
+const queueLength = 64
+
+// client is one browser watching a stream.
+type client struct {
+	send   chan []byte
+	binary bool
+}
+
+// hub fans messages of one stream out to every browser watching it.
+type hub struct {
+	mu      sync.Mutex
+	clients map[*client]struct{}
+}
+
+func newHub() *hub {
+	return &hub{clients: map[*client]struct{}{}}
+}
+
+// watching reports whether anyone is subscribed, so producers can skip work
+// nobody will see.
+func (h *hub) watching() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients) > 0
+}
+
+// publish queues m for every client, encoding it only in the formats in use.
+func (h *hub) publish(m message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var text, bin []byte
+	for c := range h.clients {
+		var data []byte
+		if c.binary {
+			if bin == nil {
+				bin = m.binary()
+			}
+			data = bin
+		} else {
+			if text == nil {
+				var err error
+				if text, err = m.text(); err != nil {
+					log.Printf("dashboard: encoding message: %v", err)
+					return
+				}
+			}
+			data = text
+		}
+		select {
+		case c.send <- data:
+		default:
+			// Dropped, the client will catch up with the next message
+		}
+	}
+}
+
+// serve streams to c over ws until either side closes.
+func (h *hub) serve(ws *conn, binary bool) {
+	c := &client{send: make(chan []byte, queueLength), binary: binary}
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, c)
+		h.mu.Unlock()
+		ws.close()
+	}()
+
+	op := byte(opText)
+	if binary {
+		op = opBinary
+	}
+	for {
+		select {
+		case data := <-c.send:
+			if err := ws.write(op, data); err != nil {
+				return
+			}
+		case <-ws.closed:
+			return
+		}
+	}
+}
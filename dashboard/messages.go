@@ -0,0 +1,170 @@
+package dashboard
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"time"
+
+	"hackathon/scenario"
+	"hackathon/systems"
+	"voltmeter/acquire"
+)
+
+// Binary messages start with one of these kinds. Everything after it is
+// little-endian: DataView defaults to big-endian, so the client must pass
+// littleEndian = true to its getters. Typed arrays such as Float32Array use
+// the platform's order, which is little-endian on every browser in use.
+const (
+	kindReading = 1
+	kindGrid    = 2
+	kindPoints  = 3
+)
+
+// message is something published to browsers, encoded once per format no
+// matter how many of them are watching.
+type message interface {
+	text() ([]byte, error)
+	binary() []byte
+}
+
+// reading is a voltmeter reading with the names of its channels.
+type reading struct {
+	acquire.Reading
+	names []string
+}
+
+// readingJSON is the text form of a reading.
+type readingJSON struct {
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	Seq      uint16    `json:"seq"`
+	Ticks    *uint32   `json:"ticks,omitempty"`
+	Channels []int     `json:"channels"`
+	Names    []string  `json:"names"`
+	Volts    []float64 `json:"volts"`
+}
+
+func (r reading) text() ([]byte, error) {
+	m := readingJSON{Type: "reading", Time: r.Received, Seq: r.Seq, Channels: r.Channels, Names: r.names, Volts: r.Volts}
+	if r.Timed {
+		m.Ticks = &r.Ticks
+	}
+	return json.Marshal(m)
+}
+
+// binary lays a reading out as: kind, channel count n, the time in Unix
+// seconds as a float64, n pin numbers as bytes, then n float32 voltages.
+func (r reading) binary() []byte {
+	n := len(r.Channels)
+	b := make([]byte, 10+5*n)
+	b[0], b[1] = kindReading, byte(n)
+	unix := float64(r.Received.UnixNano()) / 1e9
+	binary.LittleEndian.PutUint64(b[2:], math.Float64bits(unix))
+	for i, pin := range r.Channels {
+		b[10+i] = byte(pin)
+		binary.LittleEndian.PutUint32(b[10+n+4*i:], math.Float32bits(float32(r.Volts[i])))
+	}
+	return b
+}
+
+// Frame is a snapshot of the simulated wavefunction: either a grid of
+// values over a rectangle, or values at scattered points.
+type Frame struct {
+	Time     float64 `json:"time"`     // simulated time
+	Quantity string  `json:"quantity"` // what the values are, one of systems.Quantities
+
+	// Grid frames hold Width×Height values in rows from Min[1] up to Max[1],
+	// each row from Min[0] to Max[0]
+	Width  int        `json:"width,omitempty"`
+	Height int        `json:"height,omitempty"`
+	Min    [2]float64 `json:"min"`
+	Max    [2]float64 `json:"max"`
+
+	// Point frames hold a value for each position
+	Points [][3]float64 `json:"points,omitempty"`
+	Values []float64    `json:"values"`
+}
+
+// GridFrame evaluates sys at time t on a width×height grid over the x-y
+// extent of the scenario's sampled box, through the middle of it in z.
+func GridFrame(scn *scenario.Scenario, sys systems.Evaluator, t float64, width, height int) Frame {
+	min, max := scn.Extent()
+	f := Frame{Time: t, Quantity: scn.Display, Width: width, Height: height,
+		Min: [2]float64{min[0], min[1]}, Max: [2]float64{max[0], max[1]}}
+	z := (min[2] + max[2]) / 2
+	f.Values = make([]float64, width*height)
+	for j := 0; j < height; j++ {
+		y := min[1] + (float64(j)+0.5)/float64(height)*(max[1]-min[1])
+		for i := 0; i < width; i++ {
+			x := min[0] + (float64(i)+0.5)/float64(width)*(max[0]-min[0])
+			f.Values[j*width+i] = systems.Quantity(sys.Evaluate(x, y, z, t), scn.Display)
+		}
+	}
+	return f
+}
+
+// PointFrame evaluates sys at time t at points drawn by the scenario's
+// sampling strategy.
+func PointFrame(scn *scenario.Scenario, sys systems.Evaluator, points [][]float64, t float64) Frame {
+	min, max := scn.Extent()
+	f := Frame{Time: t, Quantity: scn.Display, Min: [2]float64{min[0], min[1]}, Max: [2]float64{max[0], max[1]}}
+	f.Points = make([][3]float64, len(points))
+	f.Values = make([]float64, len(points))
+	for i, p := range points {
+		f.Points[i] = [3]float64{p[0], p[1], p[2]}
+		f.Values[i] = systems.Quantity(sys.Evaluate(p[0], p[1], p[2], t), scn.Display)
+	}
+	return f
+}
+
+// frameJSON adds the message type to a frame.
+type frameJSON struct {
+	Type string `json:"type"`
+	Frame
+}
+
+func (f Frame) text() ([]byte, error) {
+	kind := "grid"
+	if f.Points != nil {
+		kind = "points"
+	}
+	return json.Marshal(frameJSON{Type: kind, Frame: f})
+}
+
+// binary lays a frame out as: kind, three bytes of padding, the time as a
+// float64, then for a grid its width and height as uint32, min and max as
+// four float32 and the values as float32; for points their count as uint32
+// and x, y, z, value as four float32 each.
+func (f Frame) binary() []byte {
+	var b []byte
+	put := func(v float64) {
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(v)))
+	}
+	if f.Points == nil {
+		b = make([]byte, 4, 36+4*len(f.Values))
+		b[0] = kindGrid
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(f.Time))
+		b = binary.LittleEndian.AppendUint32(b, uint32(f.Width))
+		b = binary.LittleEndian.AppendUint32(b, uint32(f.Height))
+		put(f.Min[0])
+		put(f.Min[1])
+		put(f.Max[0])
+		put(f.Max[1])
+		for _, v := range f.Values {
+			put(v)
+		}
+		return b
+	}
+	b = make([]byte, 4, 16+16*len(f.Points))
+	b[0] = kindPoints
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(f.Time))
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(f.Points)))
+	for i, p := range f.Points {
+		put(p[0])
+		put(p[1])
+		put(p[2])
+		put(f.Values[i])
+	}
+	return b
+}
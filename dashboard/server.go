@@ -0,0 +1,114 @@
+// Package dashboard serves a web page showing live voltmeter readings and
+// snapshots of the simulated wavefunction, streamed over WebSocket as JSON
+// or binary frames, so the lab can watch from a browser without the g3n
+// toolchain.
+package dashboard
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"voltmeter/acquire"
+)
+
+//go:embed static
+var static embed.FS
+
+// Server is an http.Handler serving the dashboard page at / and streams at
+// /ws/readings and /ws/frames. Streams send JSON text frames, or binary
+// frames when the URL has ?format=binary; messages.go describes both.
+type Server struct {
+	Name func(int) string // label of an ADC pin, ADC<n> when nil
+
+	readings, frames *hub
+	mux              *http.ServeMux
+
+	mu   sync.Mutex
+	info Info
+}
+
+// Info describes what is being shown, for the page's heading.
+type Info struct {
+	Title  string `json:"title"`  // usually the scenario's name
+	Source string `json:"source"` // where readings come from
+}
+
+// New returns a server with nothing published yet.
+func New() *Server {
+	s := &Server{readings: newHub(), frames: newHub(), mux: http.NewServeMux()}
+	page, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+	s.mux.Handle("/", http.FileServer(http.FS(page)))
+	s.mux.HandleFunc("/info", s.serveInfo)
+	s.mux.HandleFunc("/ws/readings", func(w http.ResponseWriter, r *http.Request) {
+		s.stream(s.readings, w, r)
+	})
+	s.mux.HandleFunc("/ws/frames", func(w http.ResponseWriter, r *http.Request) {
+		s.stream(s.frames, w, r)
+	})
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// SetInfo sets what /info returns as JSON.
+func (s *Server) SetInfo(info Info) {
+	s.mu.Lock()
+	s.info = info
+	s.mu.Unlock()
+}
+
+func (s *Server) serveInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	info := s.info
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// stream upgrades a request and feeds it from h.
+func (s *Server) stream(h *hub, w http.ResponseWriter, r *http.Request) {
+	ws, err := upgrade(w, r)
+	if err != nil {
+		log.Printf("dashboard: %s: %v", r.RemoteAddr, err)
+		return
+	}
+	h.serve(ws, r.URL.Query().Get("format") == "binary")
+}
+
+// PublishReading sends a reading to every browser watching readings.
+func (s *Server) PublishReading(r acquire.Reading) {
+	if !s.readings.watching() {
+		return
+	}
+	names := make([]string, len(r.Channels))
+	for i, pin := range r.Channels {
+		if s.Name != nil {
+			names[i] = s.Name(pin)
+		} else {
+			names[i] = "ADC" + strconv.Itoa(pin)
+		}
+	}
+	s.readings.publish(reading{Reading: r, names: names})
+}
+
+// WatchingFrames reports whether any browser wants frames, so the caller
+// can skip evaluating the wavefunction when nobody does.
+func (s *Server) WatchingFrames() bool {
+	return s.frames.watching()
+}
+
+// PublishFrame sends a wavefunction snapshot to every browser watching
+// frames.
+func (s *Server) PublishFrame(f Frame) {
+	s.frames.publish(f)
+}
@@ -0,0 +1,198 @@
+package dashboard
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The dashboard only pushes to browsers, so this is the small part of
+// RFC 6455 a server needs for that: the handshake, unfragmented outgoing
+// frames, and answering the control frames a browser sends.
+
+// websocketGUID is appended to the client's key to prove the handshake was
+// understood.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Frame opcodes
+const (
+	opText   = 0x1
+	opBinary = 0x2
+	opClose  = 0x8
+	opPing   = 0x9
+	opPong   = 0xA
+)
+
+// writeTimeout bounds how long a stalled browser can hold up its writer.
+const writeTimeout = 10 * time.Second
+
+// maxIncoming is the largest frame accepted from a browser, which has
+// nothing to send but control frames.
+const maxIncoming = 4096
+
+// conn is an upgraded WebSocket connection.
+type conn struct {
+	net.Conn
+	rw     *bufio.ReadWriter
+	mu     sync.Mutex // serialises frames written by the sender and by read
+	closed chan struct{}
+	once   sync.Once
+}
+
+// upgrade completes the opening handshake and takes over the connection.
+func upgrade(w http.ResponseWriter, r *http.Request) (*conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	switch {
+	case r.Method != http.MethodGet:
+		return nil, reject(w, http.StatusMethodNotAllowed, "websocket needs a GET request")
+	case !headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket"):
+		return nil, reject(w, http.StatusBadRequest, "not a websocket upgrade")
+	case r.Header.Get("Sec-WebSocket-Version") != "13":
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, reject(w, http.StatusUpgradeRequired, "unsupported websocket version")
+	case key == "":
+		return nil, reject(w, http.StatusBadRequest, "missing Sec-WebSocket-Key")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, reject(w, http.StatusInternalServerError, "connection cannot be upgraded")
+	}
+	nc, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	c := &conn{Conn: nc, rw: rw, closed: make(chan struct{})}
+	go c.read()
+	return c, nil
+}
+
+// reject answers a request that cannot be upgraded.
+func reject(w http.ResponseWriter, status int, reason string) error {
+	http.Error(w, reason, status)
+	return errors.New(reason)
+}
+
+// headerHas reports whether a comma separated header lists token.
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// write sends one unfragmented frame.
+func (c *conn) write(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	header := []byte{0x80 | op, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	n := len(payload)
+	switch {
+	case n < 126:
+		header[1] = byte(n)
+		header = header[:2]
+	case n <= 0xFFFF:
+		header[1] = 126
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+		header = header[:4]
+	default:
+		header[1] = 127
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+	c.SetWriteDeadline(time.Now().Add(writeTimeout))
+	c.rw.Write(header)
+	c.rw.Write(payload)
+	return c.rw.Flush()
+}
+
+// read answers pings and closes until the browser goes away, then marks the
+// connection closed.
+func (c *conn) read() {
+	defer c.close()
+	for {
+		op, payload, err := c.next()
+		if err != nil {
+			return
+		}
+		switch op {
+		case opPing:
+			if c.write(opPong, payload) != nil {
+				return
+			}
+		case opClose:
+			c.write(opClose, payload)
+			return
+		}
+	}
+}
+
+// next reads one frame from the browser, unmasking its payload.
+func (c *conn) next() (op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.rw, head[:]); err != nil {
+		return 0, nil, err
+	}
+	op = head[0] & 0x0F
+	if head[1]&0x80 == 0 {
+		return 0, nil, errors.New("unmasked frame from client")
+	}
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > maxIncoming {
+		return 0, nil, fmt.Errorf("client frame of %d bytes", n)
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return op, payload, nil
+}
+
+// close shuts the connection once, from either side.
+func (c *conn) close() {
+	c.once.Do(func() {
+		close(c.closed)
+		c.Conn.Close()
+	})
+}
@@ -3,14 +3,18 @@ module hackathon
 go 1.21.1
 
 require (
-	github.com/g3n/engine v0.2.0 // indirect
+	github.com/g3n/engine v0.2.0
+	golang.org/x/image v0.14.0
+	gonum.org/v1/gonum v0.15.0
+	gopkg.in/yaml.v2 v2.4.0
+	voltmeter v0.0.0-00010101000000-000000000000
+)
+
+require (
 	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20210410170116-ea3d685f79fb // indirect
-	github.com/go-gl/mathgl v1.1.0 // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
 	github.com/jacobsa/go-serial v0.0.0-20180131005756-15cf729a72d4 // indirect
-	github.com/mrmorphic/hwio v0.0.0-20180519033216-11ea3f481a14 // indirect
-	golang.org/x/image v0.14.0 // indirect
 	golang.org/x/sys v0.19.0 // indirect
-	gonum.org/v1/gonum v0.15.0 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
+
+replace voltmeter => ./ArduinoCode
@@ -0,0 +1,86 @@
+// Package hud draws a text overlay of live observables in the corner of a
+// g3n window, so conservation of the norm and energy can be checked while
+// the animation runs.
+package hud
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/gui/assets"
+	"github.com/g3n/engine/math32"
+	"github.com/g3n/engine/text"
+
+	"hackathon/observables"
+)
+
+const (
+	margin = 10
+	width  = 260
+)
+
+// HUD is a translucent panel holding one line per observable.
+type HUD struct {
+	*gui.Panel
+	label *gui.Label
+	notes []string // extra lines shown under the observables
+}
+
+// New creates an empty overlay. Call Place when the window is resized and
+// Update whenever new values are available.
+func New() *HUD {
+	h := &HUD{Panel: gui.NewPanel(width, 0)}
+	h.SetColor4(&math32.Color4{R: 0.1, G: 0.1, B: 0.1, A: 0.7})
+	h.SetPaddings(6, 8, 6, 8)
+	h.label = gui.NewLabelWithFont("", newFont())
+	h.label.SetColor(&math32.Color{R: 1, G: 1, B: 1})
+	h.Add(h.label)
+	h.Update(0, observables.Stats{}, 0)
+	return h
+}
+
+// newFont loads a monospaced font so the columns of values line up.
+func newFont() *text.Font {
+	font, err := text.NewFontFromData(assets.MustAsset("fonts/FreeMono.ttf"))
+	if err != nil {
+		panic(err)
+	}
+	font.SetPointSize(14)
+	return font
+}
+
+// Place moves the overlay to the top right corner of a window of the given
+// width.
+func (h *HUD) Place(windowWidth int) {
+	h.SetPosition(float32(windowWidth)-h.Width()-margin, margin)
+}
+
+// SetNotes sets extra lines, such as properties of the system, shown under
+// the observables from the next Update on.
+func (h *HUD) SetNotes(lines ...string) {
+	h.notes = lines
+}
+
+// Update shows the simulated time t, the observables measured over the
+// rendered sample set and the measured frame rate.
+func (h *HUD) Update(t float64, st observables.Stats, fps float64) {
+	text := fmt.Sprintf(
+		"t       %.4g\n"+
+			"norm    %.6f\n"+
+			"<x>     %.4g\n"+
+			"<y>     %.4g\n"+
+			"<z>     %.4g\n"+
+			"<E>     %.6g\n"+
+			"dx      %.4g\n"+
+			"dy      %.4g\n"+
+			"dz      %.4g\n"+
+			"fps     %.1f",
+		t, st.Norm, st.Mean[0], st.Mean[1], st.Mean[2], st.Energy,
+		st.Spread[0], st.Spread[1], st.Spread[2], fps)
+	if len(h.notes) > 0 {
+		text += "\n" + strings.Join(h.notes, "\n")
+	}
+	h.label.SetText(text)
+	h.SetContentSize(h.label.Width(), h.label.Height())
+}
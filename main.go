@@ -1,9 +1,9 @@
 package main
 
 import (
-	"math"
-	"math/cmplx"
-	"math/rand"
+	"flag"
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/g3n/engine/app"
@@ -21,9 +21,31 @@ import (
 	"github.com/g3n/engine/util/helper"
 	"github.com/g3n/engine/window"
 	"gonum.org/v1/gonum/stat"
+
+	"hackathon/axes"
+	"hackathon/axes/axes3d"
+	"hackathon/colormap"
+	"hackathon/controls"
+	"hackathon/hud"
+	"hackathon/observables"
+	"hackathon/scenario"
+	"hackathon/systems"
+	"voltmeter/acquire"
 )
 
+// cmap is the colormap selected by the scenario
+var cmap colormap.Map = colormap.RedBlue
+
 func main() {
+	// Load the scenario describing this run, with the voltmeter flags for any inputs it binds
+	settings := acquire.RegisterFlags(flag.CommandLine)
+	scn := scenario.FromFlags(defaultScenario())
+	sys, err := scn.Build()
+	if err != nil {
+		panic(err)
+	}
+	cmap = scn.ColorMap()
+
 	// Create application and scene
 	a := app.App()
 	scene := core.NewNode()
@@ -34,12 +56,18 @@ func main() {
 
 	// Create perspective camera
 	cam := camera.New(1)
-	cam.SetPosition(0, 0, 3)
+	cam.SetPosition(float32(scn.Camera.Position[0]), float32(scn.Camera.Position[1]), float32(scn.Camera.Position[2]))
+	cam.LookAt(&math32.Vector3{X: float32(scn.Camera.Target[0]), Y: float32(scn.Camera.Target[1]), Z: float32(scn.Camera.Target[2])}, &math32.Vector3{Y: 1})
 	scene.Add(cam)
 
 	// Set up orbit control for the camera
 	camera.NewOrbitControl(cam)
 
+	// Create the overlay of live observables
+	overlay := hud.New()
+	overlay.SetNotes(notes(sys)...)
+	scene.Add(overlay)
+
 	// Set up callback to update viewport and camera aspect ratio when the window is resized
 	onResize := func(evname string, ev interface{}) {
 		// Get framebuffer size and update viewport accordingly
@@ -47,21 +75,30 @@ func main() {
 		a.Gls().Viewport(0, 0, int32(width), int32(height))
 		// Update the camera's aspect ratio
 		cam.SetAspect(float32(width) / float32(height))
+		// Keep the overlay in the top right corner
+		overlay.Place(width)
 	}
 	a.Subscribe(window.OnWindowSize, onResize)
 	onResize("", nil)
 
-	// axis length
-	//TODO function call to set maxs
-	xLength := float64(15)
-	zLength := float64(15)
-	yLength := float64(15)
-
-	//if max is 10 min is -5
-
-	createGraph(scene, xLength, zLength, yLength)
-	points := generateRandomCoords(20000, 0, xLength, 0, yLength, 0, zLength)
-	mats := plotPoints(scene, points)
+	// Axes span the sampled region, the points get their own node so the
+	// control panel can replace them
+	graph := createGraph(scene, scn.AxisLayout(), scn.Axes.Ticks)
+	cloud := core.NewNode()
+	scene.Add(cloud)
+	// The overlay weighs the points by the density they were drawn from,
+	// worked out once when they are drawn
+	sampledAt := 0.0
+	points := scn.Sample(sys, sampledAt)
+	set := scn.SampleSet(sys, points, sampledAt)
+	mats := plotPoints(cloud, points)
+
+	// Add the control panel for editing the scenario live
+	panel := controls.New(scn)
+	scene.Add(panel)
+
+	// Voltmeter channels bound by the scenario steer it alongside the panel
+	knobs := controls.KnobsFromFlags(scn, settings)
 
 	// Create and add lights to the scene
 	scene.Add(light.NewAmbient(&math32.Color{1.0, 1.0, 1.0}, 0.8))
@@ -75,7 +112,9 @@ func main() {
 	// Set background color to gray
 	a.Gls().ClearColor(0.5, 0.5, 0.5, 1.0)
 
-	//startTime := float64(time.Now().Unix())
+	// Simulated time runs at the scenario's time scale, added up frame by
+	// frame so moving the slider changes its pace without a jump
+	t := 0.0
 	a.Run(func(rend *renderer.Renderer, deltaTime time.Duration) {
 		// Start measuring this frame
 		rater.Start()
@@ -89,15 +128,52 @@ func main() {
 			panic(err)
 		}
 
+		t += deltaTime.Seconds() * scn.Solver.TimeScale
+
+		// Apply edits made in the control panel or by the voltmeter since the last frame
+		change := panel.Poll() | knobs.Poll()
+		if change.Has(controls.Rebuild) {
+			// A setting the system cannot take, such as a well too shallow
+			// for the chosen state, keeps the last one that worked
+			if next, err := scn.Build(); err != nil {
+				log.Print(err)
+			} else {
+				sys = next
+				overlay.SetNotes(notes(sys)...)
+			}
+		}
+		if change.Has(controls.Resample) {
+			scene.Remove(graph)
+			graph.DisposeChildren(true)
+			graph = createGraph(scene, scn.AxisLayout(), scn.Axes.Ticks)
+			scene.Remove(cloud)
+			cloud.DisposeChildren(true)
+			cloud = core.NewNode()
+			scene.Add(cloud)
+			sampledAt = t
+			points = scn.Sample(sys, sampledAt)
+			set = scn.SampleSet(sys, points, sampledAt)
+			mats = plotPoints(cloud, points)
+		}
+		if change.Has(controls.Recolor) {
+			cmap = scn.ColorMap()
+		}
+
 		var vals []float64
 		for i := 0; i < len(points); i++ {
-			vals = append(vals, points[i][2])
+			val := sys.Evaluate(points[i][0], points[i][1], points[i][2], t)
+			vals = append(vals, systems.Quantity(val, scn.Display))
 		}
 		vals = NormalizeVals(vals)
 		for i := 0; i < len(mats); i++ {
 			mats[i].SetColor(GenerateColorOnGradient((0 + vals[i]*(1))))
 		}
 
+		// Refresh the overlay twice a second from the points being drawn
+		if fps, _, ok := rater.FPS(time.Second / 2); ok {
+			overlay.Update(t, observables.Measure(sys, set, t, scn.Hamiltonian()), fps)
+		}
+
 		// Update GUI timers
 		gui.Manager().TimerManager.ProcessTimers()
 
@@ -106,46 +182,40 @@ func main() {
 	})
 }
 
-func calculateWaveFunction(x, y, z, t float64) complex128 {
-	// Constants for the infinite square well problem
-	a := 15.0                   // width of the well
-	nx, ny, nz := 3.0, 3.0, 3.0 // quantum numbers for each dimension
-	hbar := 1.0545718e-34       // reduced Planck's constant
-	m := 9.10938356e-31         // mass of the particle
-
-	// Calculate the real part of the wave function for each dimension
-	realPartX := math.Sqrt(2/a) * math.Sin(nx*math.Pi*x/a)
-	realPartY := math.Sqrt(2/a) * math.Sin(ny*math.Pi*y/a)
-	realPartZ := math.Sqrt(2/a) * math.Sin(nz*math.Pi*z/a)
-
-	// Calculate the imaginary part of the wave function
-	imaginaryPart := -1 * (nx*nx + ny*ny + nz*nz) * math.Pi * math.Pi * hbar * t / (2 * m * a * a)
-
-	// Combine the real and imaginary parts to get the full wave function
-	waveFunction := complex(realPartX*realPartY*realPartZ, 0) * cmplx.Exp(complex(0, imaginaryPart))
+// defaultScenario is the 3D infinite square well this viewer shows when no
+// scenario file is given
+func defaultScenario() *scenario.Scenario {
+	return &scenario.Scenario{
+		Name: "infinite square well",
+		System: scenario.System{
+			Kind:    "infinite-well",
+			Width:   []float64{15, 15, 15}, // width of the well
+			Quantum: []int{3, 3, 3},        // quantum numbers for each dimension
+			Units:   "si",
+		},
+		Sampling: scenario.Sampling{Strategy: "random", Points: 20000},
+	}
+}
 
-	return waveFunction
+// notes returns the overlay lines describing sys, the probability found in
+// the walls for wells that leak and the tunnelling period of a double well
+func notes(sys systems.System) []string {
+	if w, ok := sys.(interface{ Leakage() float64 }); ok {
+		return []string{fmt.Sprintf("outside %.6f", w.Leakage())}
+	}
+	if w, ok := sys.(interface{ Period() float64 }); ok {
+		return []string{fmt.Sprintf("period  %.4g", w.Period())}
+	}
+	return nil
 }
 
-func createGraph(scene *core.Node, xLength, zLength, yLength float64) {
-	// x axis
-	geomX := geometry.NewBox(float32(xLength), 0.05, 0.05)
-	matX := material.NewStandard(math32.NewColor("DarkBlue"))
-	meshX := graphic.NewMesh(geomX, matX)
-	meshX.SetPosition(float32(xLength/2), 0, 0)
-	scene.Add(meshX)
-	// z axis
-	geomZ := geometry.NewBox(0.05, float32(zLength), 0.05)
-	matZ := material.NewStandard(math32.NewColor("DarkBlue"))
-	meshZ := graphic.NewMesh(geomZ, matZ)
-	meshZ.SetPosition(0, float32(zLength/2), 0)
-	scene.Add(meshZ)
-	// y axis
-	geomY := geometry.NewBox(0.05, 0.05, float32(yLength))
-	matY := material.NewStandard(math32.NewColor("DarkBlue"))
-	meshY := graphic.NewMesh(geomY, matY)
-	meshY.SetPosition(0, 0, float32(yLength/2))
-	scene.Add(meshY)
+// createGraph draws labelled axes for the physical x, y and z ranges, with z up the screen
+func createGraph(scene *core.Node, layout [3]axes.Axis, ticks int) *core.Node {
+	cfg := axes3d.DefaultConfig([3]axes.Axis{layout[0], layout[2], layout[1]})
+	cfg.Ticks = ticks
+	graph := axes3d.New(cfg)
+	scene.Add(graph)
+	return graph
 }
 
 func plotPoints(scene *core.Node, points [][]float64) []*material.Standard {
@@ -162,36 +232,9 @@ func plotPoints(scene *core.Node, points [][]float64) []*material.Standard {
 	return Array
 }
 
-// generateRandomCoords generates random arrays of float64 within specified bounds
-func generateRandomCoords(numCoords int, minX, maxX, minY, maxY, minZ, maxZ float64) [][]float64 {
-	// Create the 2D array to store the random arrays
-	result := make([][]float64, numCoords)
-	r := rand.New(rand.NewSource(38))
-
-	// Generate random values for each array
-	for i := 0; i < numCoords; i++ {
-		result[i] = make([]float64, 3)
-		result[i][0] = minX + r.Float64()*(maxX-minX) // Random value for x
-		result[i][1] = minY + r.Float64()*(maxY-minY) // Random value for y
-		result[i][2] = minZ + r.Float64()*(maxZ-minZ) // Random value for z
-	}
-	return result
-}
-
-// GenerateColorOnGradient generates a color on a gradient from red to blue based on the input value (0 to 1)
+// GenerateColorOnGradient generates a color on the scenario's colormap based on the input value (0 to 1)
 func GenerateColorOnGradient(value float64) *math32.Color {
-	// Ensure the value is within the range of 0 to 1
-	if value < 0 {
-		value = 0
-	} else if value > 1 {
-		value = 1
-	}
-
-	// Interpolate between red and blue based on the input value
-	red := 1 - float32(value)
-	blue := float32(value)
-	green := float32(0)
-
+	red, green, blue := cmap(value)
 	return &math32.Color{R: red, G: green, B: blue}
 }
 func NormalizeVals(vals []float64) []float64 {
@@ -0,0 +1,175 @@
+package observables
+
+import (
+	"math"
+	"math/cmplx"
+
+	"hackathon/sampling"
+)
+
+// Estimate is a numerical result with an estimate of its absolute error.
+type Estimate struct {
+	Value float64
+	Error float64
+}
+
+// Complex is a complex numerical result with an estimate of its absolute
+// error.
+type Complex struct {
+	Value complex128
+	Error float64
+}
+
+// Integrator integrates several functions over the same nodes at once, so
+// that ψ is only evaluated once per node however many observables are wanted.
+type Integrator interface {
+	// Integrate calls f once per node with a slice of n integrand values to
+	// fill in, and returns the integral of each with its error.
+	Integrate(n int, f func(p [3]float64, out []complex128)) []Complex
+	// Box returns the region integrated over.
+	Box() (min, max [3]float64)
+}
+
+// Quadrature is composite Simpson quadrature on a tensor product grid. The
+// error is estimated by Richardson extrapolation against the same rule on
+// every other node, which costs no extra evaluations.
+type Quadrature struct {
+	Min, Max  [3]float64
+	Intervals int // intervals per axis, rounded up to a multiple of 4
+}
+
+// NewQuadrature returns Simpson quadrature over the box [min, max]. Axes with
+// no extent are not integrated over.
+func NewQuadrature(min, max [3]float64, intervals int) *Quadrature {
+	if intervals < 4 {
+		intervals = 4
+	}
+	intervals = (intervals + 3) / 4 * 4
+	return &Quadrature{Min: min, Max: max, Intervals: intervals}
+}
+
+// Box returns the region integrated over.
+func (q *Quadrature) Box() (min, max [3]float64) {
+	return q.Min, q.Max
+}
+
+// axis returns the nodes along one axis with their fine and coarse Simpson
+// weights.
+func (q *Quadrature) axis(d int) (nodes, fine, coarse []float64) {
+	if q.Max[d] <= q.Min[d] {
+		return []float64{q.Min[d]}, []float64{1}, []float64{1}
+	}
+	n := q.Intervals
+	h := (q.Max[d] - q.Min[d]) / float64(n)
+	nodes = make([]float64, n+1)
+	fine = make([]float64, n+1)
+	coarse = make([]float64, n+1)
+	for i := 0; i <= n; i++ {
+		nodes[i] = q.Min[d] + float64(i)*h
+		fine[i] = simpson(i, n) * h / 3
+		if i%2 == 0 {
+			coarse[i] = simpson(i/2, n/2) * 2 * h / 3
+		}
+	}
+	return nodes, fine, coarse
+}
+
+// simpson returns the Simpson coefficient 1, 4, 2, ..., 4, 1 of node i of n.
+func simpson(i, n int) float64 {
+	switch {
+	case i == 0 || i == n:
+		return 1
+	case i%2 == 1:
+		return 4
+	}
+	return 2
+}
+
+// Integrate applies the rule to every integrand.
+func (q *Quadrature) Integrate(n int, f func(p [3]float64, out []complex128)) []Complex {
+	var nodes, fine, coarse [3][]float64
+	for d := 0; d < 3; d++ {
+		nodes[d], fine[d], coarse[d] = q.axis(d)
+	}
+
+	fineSum := make([]complex128, n)
+	coarseSum := make([]complex128, n)
+	out := make([]complex128, n)
+	for i, x := range nodes[0] {
+		for j, y := range nodes[1] {
+			for k, z := range nodes[2] {
+				wf := fine[0][i] * fine[1][j] * fine[2][k]
+				wc := coarse[0][i] * coarse[1][j] * coarse[2][k]
+				for m := range out {
+					out[m] = 0
+				}
+				f([3]float64{x, y, z}, out)
+				for m, v := range out {
+					fineSum[m] += complex(wf, 0) * v
+					coarseSum[m] += complex(wc, 0) * v
+				}
+			}
+		}
+	}
+
+	result := make([]Complex, n)
+	for m := range result {
+		result[m] = Complex{Value: fineSum[m], Error: cmplx.Abs(fineSum[m]-coarseSum[m]) / 15}
+	}
+	return result
+}
+
+// MonteCarlo returns a set of n points scattered uniformly over the box
+// [min, max], whose integrals carry a statistical error.
+func MonteCarlo(min, max [3]float64, n int, seed int64) Set {
+	set := Uniform(sampling.Uniform(n, min, max, seed), min, max)
+	set.Min, set.Max = min, max
+	return set
+}
+
+// Box returns the region the set was drawn from, or the bounding box of its
+// points when that is not known.
+func (s Set) Box() (min, max [3]float64) {
+	if s.Min != s.Max {
+		return s.Min, s.Max
+	}
+	for d := 0; d < 3; d++ {
+		min[d], max[d] = math.Inf(1), math.Inf(-1)
+	}
+	for _, p := range s.Points {
+		for d := 0; d < 3; d++ {
+			min[d] = math.Min(min[d], p[d])
+			max[d] = math.Max(max[d], p[d])
+		}
+	}
+	return min, max
+}
+
+// Integrate returns Σ w f(p) for every integrand. The error is the standard
+// error of the mean of N·w·f, which is the statistical error for random
+// sets and a rough indication for grids.
+func (s Set) Integrate(n int, f func(p [3]float64, out []complex128)) []Complex {
+	sum := make([]complex128, n)
+	sumSq := make([]float64, n)
+	out := make([]complex128, n)
+	count := float64(len(s.Points))
+	for i, p := range s.Points {
+		for m := range out {
+			out[m] = 0
+		}
+		f([3]float64{p[0], p[1], p[2]}, out)
+		for m, v := range out {
+			term := complex(s.Weights[i]*count, 0) * v
+			sum[m] += term
+			sumSq[m] += real(term)*real(term) + imag(term)*imag(term)
+		}
+	}
+
+	result := make([]Complex, n)
+	for m := range result {
+		mean := sum[m] / complex(count, 0)
+		variance := sumSq[m]/count - (real(mean)*real(mean) + imag(mean)*imag(mean))
+		result[m] = Complex{Value: mean, Error: math.Sqrt(math.Max(variance, 0) / count)}
+	}
+	return result
+}
@@ -0,0 +1,115 @@
+// Package observables estimates expectation values of a wavefunction by
+// numerical integration. Any systems.System, or a bare
+// calculateWaveFunction-style function wrapped in systems.WaveFunction, can
+// be measured.
+package observables
+
+import (
+	"hackathon/systems"
+)
+
+// Set is a set of sample points together with the volume each one stands
+// for, so that Σ w f(p) approximates ∫ f.
+type Set struct {
+	Points   [][]float64
+	Weights  []float64
+	Min, Max [3]float64 // region the points were drawn from, if known
+}
+
+// Uniform weights points scattered uniformly over the box [min, max]. Axes
+// with no extent do not contribute to the volume.
+func Uniform(points [][]float64, min, max [3]float64) Set {
+	volume := 1.0
+	for d := 0; d < 3; d++ {
+		if max[d] > min[d] {
+			volume *= max[d] - min[d]
+		}
+	}
+	set := constant(points, volume/float64(len(points)))
+	set.Min, set.Max = min, max
+	return set
+}
+
+// Grid weights points on a regular lattice with the given spacing over the
+// box [min, max].
+func Grid(points [][]float64, step float64, min, max [3]float64) Set {
+	cell := 1.0
+	for d := 0; d < 3; d++ {
+		if max[d] > min[d] {
+			cell *= step
+		}
+	}
+	set := constant(points, cell)
+	set.Min, set.Max = min, max
+	return set
+}
+
+// Importance weights points drawn with probability density proportional to
+// density, which must integrate to one, as produced by sampling.Density for
+// a normalised state.
+func Importance(points [][]float64, density func(p []float64) float64) Set {
+	set := Set{Points: points, Weights: make([]float64, len(points))}
+	for i, p := range points {
+		if d := density(p); d > 0 {
+			set.Weights[i] = 1 / (float64(len(points)) * d)
+		}
+	}
+	return set
+}
+
+func constant(points [][]float64, w float64) Set {
+	set := Set{Points: points, Weights: make([]float64, len(points))}
+	for i := range set.Weights {
+		set.Weights[i] = w
+	}
+	return set
+}
+
+// Hamiltonian holds what is needed to apply H = -ħ²/2m ∇² + V.
+type Hamiltonian struct {
+	Hbar, Mass float64
+	Step       float64 // finite difference step for the derivatives
+}
+
+// Apply returns (Hψ)(x, y, z, t) using a central difference Laplacian. The
+// potential is taken from sys when it provides one, and is zero otherwise.
+func (h Hamiltonian) Apply(sys systems.Evaluator, x, y, z, t float64) complex128 {
+	psi := sys.Evaluate(x, y, z, t)
+	d := h.Step
+	lap := (sys.Evaluate(x+d, y, z, t) + sys.Evaluate(x-d, y, z, t) +
+		sys.Evaluate(x, y+d, z, t) + sys.Evaluate(x, y-d, z, t) +
+		sys.Evaluate(x, y, z+d, t) + sys.Evaluate(x, y, z-d, t) -
+		6*psi) / complex(d*d, 0)
+	return complex(-h.Hbar*h.Hbar/(2*h.Mass), 0)*lap + potential(sys, x, y, z, psi)
+}
+
+// potential returns V(x, y, z)ψ, skipping the potential where ψ vanishes
+// since the walls of a box are infinite.
+func potential(sys systems.Evaluator, x, y, z float64, psi complex128) complex128 {
+	v, ok := sys.(interface{ Potential(x, y, z float64) float64 })
+	if !ok || psi == 0 {
+		return 0
+	}
+	return complex(v.Potential(x, y, z), 0) * psi
+}
+
+// Stats are the observables shown while a simulation runs.
+type Stats struct {
+	Norm   float64    // ∫|ψ|²
+	Mean   [3]float64 // ⟨x⟩, ⟨y⟩, ⟨z⟩
+	Spread [3]float64 // Δx, Δy, Δz
+	Energy float64    // ⟨H⟩
+}
+
+// Measure estimates the norm, position moments and energy of sys at time t
+// over the sample set. Expectation values are divided by the norm so they
+// stay meaningful when the sample set misses part of the state.
+func Measure(sys systems.Evaluator, set Set, t float64, h Hamiltonian) Stats {
+	r := Compute(sys, set, t, h)
+	st := Stats{Norm: r.Norm.Value, Energy: r.Energy.Value}
+	for d := 0; d < 3; d++ {
+		st.Mean[d] = r.X[d].Value
+		st.Spread[d] = r.DeltaX[d].Value
+	}
+	return st
+}
@@ -0,0 +1,189 @@
+package observables
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/cmplx"
+
+	"hackathon/systems"
+)
+
+// Indices of the integrands accumulated by Compute.
+const (
+	iNorm   = 0
+	iX      = 1  // x, y, z moments
+	iX2     = 4  // x², y², z² moments
+	iP      = 7  // momentum components
+	iP2     = 10 // squared momentum components
+	iEnergy = 13
+	nTerms  = 14
+)
+
+// Report holds the observables of a state at one instant. Expectation values
+// are divided by the norm. Axes the integrator does not extend along are
+// marked inactive and left zero.
+type Report struct {
+	Time        float64
+	Active      [3]bool
+	Norm        Estimate    // ∫|ψ|²
+	X           [3]Estimate // ⟨x⟩
+	X2          [3]Estimate // ⟨x²⟩
+	P           [3]Estimate // ⟨p⟩
+	P2          [3]Estimate // ⟨p²⟩
+	Energy      Estimate    // ⟨H⟩
+	DeltaX      [3]Estimate // Δx
+	DeltaP      [3]Estimate // Δp
+	Uncertainty [3]Estimate // Δx·Δp, at least ħ/2
+}
+
+// Spectral is a system that works out its own momentum and energy, such as
+// a wavepacket held on a grid, whose interpolated ψ is too coarse for
+// finite differences. The values are expectations at time t.
+type Spectral interface {
+	Moments(t float64) (p, p2 [3]float64, energy float64)
+}
+
+// Compute integrates the position, momentum and energy observables of sys at
+// time t. Derivatives are central differences with the Hamiltonian's step,
+// unless sys is Spectral and reports the momenta and energy itself.
+func Compute(sys systems.Evaluator, in Integrator, t float64, h Hamiltonian) Report {
+	min, max := in.Box()
+	var r Report
+	r.Time = t
+	for d := 0; d < 3; d++ {
+		r.Active[d] = max[d] > min[d]
+	}
+
+	spectral, isSpectral := sys.(Spectral)
+	step := h.Step
+	sums := in.Integrate(nTerms, func(p [3]float64, out []complex128) {
+		psi := sys.Evaluate(p[0], p[1], p[2], t)
+		conj := cmplx.Conj(psi)
+		density := complex(real(psi)*real(psi)+imag(psi)*imag(psi), 0)
+		out[iNorm] = density
+
+		var lap complex128
+		for d := 0; d < 3; d++ {
+			if !r.Active[d] {
+				continue
+			}
+			x := complex(p[d], 0)
+			out[iX+d] = x * density
+			out[iX2+d] = x * x * density
+			if isSpectral {
+				continue
+			}
+
+			fwd, back := p, p
+			fwd[d] += step
+			back[d] -= step
+			psiF := sys.Evaluate(fwd[0], fwd[1], fwd[2], t)
+			psiB := sys.Evaluate(back[0], back[1], back[2], t)
+			grad := (psiF - psiB) / complex(2*step, 0)
+			second := (psiF + psiB - 2*psi) / complex(step*step, 0)
+			lap += second
+
+			// p = -iħ∂, p² = -ħ²∂²
+			out[iP+d] = conj * complex(0, -h.Hbar) * grad
+			out[iP2+d] = conj * complex(-h.Hbar*h.Hbar, 0) * second
+		}
+		if isSpectral {
+			return
+		}
+		out[iEnergy] = conj * (complex(-h.Hbar*h.Hbar/(2*h.Mass), 0)*lap + potential(sys, p[0], p[1], p[2], psi))
+	})
+
+	r.Norm = Estimate{Value: real(sums[iNorm].Value), Error: sums[iNorm].Error}
+	expect := func(c Complex) Estimate {
+		return ratio(real(c.Value), c.Error, r.Norm)
+	}
+	r.Energy = expect(sums[iEnergy])
+	var p, p2 [3]float64
+	if isSpectral {
+		var energy float64
+		p, p2, energy = spectral.Moments(t)
+		r.Energy = Estimate{Value: energy}
+	}
+	for d := 0; d < 3; d++ {
+		if !r.Active[d] {
+			continue
+		}
+		r.X[d] = expect(sums[iX+d])
+		r.X2[d] = expect(sums[iX2+d])
+		r.P[d] = expect(sums[iP+d])
+		r.P2[d] = expect(sums[iP2+d])
+		if isSpectral {
+			r.P[d], r.P2[d] = Estimate{Value: p[d]}, Estimate{Value: p2[d]}
+		}
+		r.DeltaX[d] = spread(r.X[d], r.X2[d])
+		r.DeltaP[d] = spread(r.P[d], r.P2[d])
+		value := r.DeltaX[d].Value * r.DeltaP[d].Value
+		r.Uncertainty[d] = Estimate{
+			Value: value,
+			Error: r.DeltaX[d].Error*r.DeltaP[d].Value + r.DeltaX[d].Value*r.DeltaP[d].Error,
+		}
+	}
+	return r
+}
+
+// ratio divides an integral by the norm, propagating both errors.
+func ratio(value, err float64, norm Estimate) Estimate {
+	if norm.Value == 0 {
+		return Estimate{}
+	}
+	return Estimate{
+		Value: value / norm.Value,
+		Error: err/math.Abs(norm.Value) + math.Abs(value)*norm.Error/(norm.Value*norm.Value),
+	}
+}
+
+// spread returns sqrt(⟨a²⟩ - ⟨a⟩²) with first order error propagation.
+func spread(mean, square Estimate) Estimate {
+	variance := square.Value - mean.Value*mean.Value
+	if variance <= 0 {
+		return Estimate{Error: math.Sqrt(square.Error + 2*math.Abs(mean.Value)*mean.Error)}
+	}
+	value := math.Sqrt(variance)
+	return Estimate{Value: value, Error: (square.Error + 2*math.Abs(mean.Value)*mean.Error) / (2 * value)}
+}
+
+// Overlap returns ⟨φ|ψ⟩ = ∫φ*ψ at time t.
+func Overlap(phi, psi systems.Evaluator, in Integrator, t float64) Complex {
+	return in.Integrate(1, func(p [3]float64, out []complex128) {
+		out[0] = cmplx.Conj(phi.Evaluate(p[0], p[1], p[2], t)) * psi.Evaluate(p[0], p[1], p[2], t)
+	})[0]
+}
+
+// WriteText writes the report as an aligned table of values and errors.
+func (r Report) WriteText(w io.Writer) error {
+	names := [3]string{"x", "y", "z"}
+	rows := []struct {
+		name string
+		e    Estimate
+	}{{"t", Estimate{Value: r.Time}}, {"norm", r.Norm}, {"<H>", r.Energy}}
+	for d := 0; d < 3; d++ {
+		if !r.Active[d] {
+			continue
+		}
+		n := names[d]
+		rows = append(rows, []struct {
+			name string
+			e    Estimate
+		}{
+			{"<" + n + ">", r.X[d]},
+			{"<" + n + "^2>", r.X2[d]},
+			{"<p" + n + ">", r.P[d]},
+			{"<p" + n + "^2>", r.P2[d]},
+			{"d" + n, r.DeltaX[d]},
+			{"dp" + n, r.DeltaP[d]},
+			{"d" + n + "*dp" + n, r.Uncertainty[d]},
+		}...)
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintf(w, "%-10s %14.6g  ± %.2g\n", row.name, row.e.Value, row.e.Error); err != nil {
+			return err
+		}
+	}
+	return nil
+}
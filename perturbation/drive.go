@@ -0,0 +1,87 @@
+// Package perturbation drives a system with a time-dependent perturbation
+// H'(r, t) = W(r) f(t) and follows the coefficients of its eigenstates.
+// Writing ψ = Σ cₙ(t) e^{-iEₙt/ħ} φₙ, the Schrödinger equation becomes
+//
+//	iħ dcₘ/dt = f(t) Σₙ Wₘₙ e^{iωₘₙt} cₙ,  ωₘₙ = (Eₘ - Eₙ)/ħ,
+//
+// which is integrated exactly over a truncated set of levels, alongside
+// the first-order result of perturbation theory and the Rabi and Fermi
+// golden rule predictions to compare with it.
+package perturbation
+
+import (
+	"fmt"
+	"math"
+)
+
+// Drive is the time dependence f(t) of the perturbation.
+type Drive struct {
+	Kind     string  // "step", "harmonic" or "pulse"
+	Omega    float64 // angular frequency of a harmonic drive or of a pulse's carrier
+	Start    float64 // when a step or harmonic drive switches on, or the peak of a pulse
+	Duration float64 // standard deviation τ of a pulse's Gaussian envelope
+}
+
+// Validate reports a drive of unknown kind or missing its frequency or
+// duration.
+func (d Drive) Validate() error {
+	switch d.Kind {
+	case "step":
+	case "harmonic":
+		if d.Omega <= 0 {
+			return fmt.Errorf("perturbation: harmonic drive frequency must be positive, got %v", d.Omega)
+		}
+	case "pulse":
+		if d.Duration <= 0 {
+			return fmt.Errorf("perturbation: pulse duration must be positive, got %v", d.Duration)
+		}
+		if d.Omega < 0 {
+			return fmt.Errorf("perturbation: pulse carrier frequency must not be negative, got %v", d.Omega)
+		}
+	default:
+		return fmt.Errorf("perturbation: unknown drive %q, want step, harmonic or pulse", d.Kind)
+	}
+	return nil
+}
+
+// At returns f(t): 1 once a step has switched on, cos ωt once a harmonic
+// drive has, and e^{-(t-t₀)²/2τ²} cos ωt for a pulse.
+func (d Drive) At(t float64) float64 {
+	switch d.Kind {
+	case "step":
+		if t >= d.Start {
+			return 1
+		}
+	case "harmonic":
+		if t >= d.Start {
+			return math.Cos(d.Omega * t)
+		}
+	case "pulse":
+		u := (t - d.Start) / d.Duration
+		return math.Exp(-u*u/2) * math.Cos(d.Omega*t)
+	}
+	return 0
+}
+
+// Exposure returns ∫ a(t')² dt' up to t for the envelope a of the drive,
+// 1 once a step or harmonic drive has switched on and the Gaussian of a
+// pulse: the time over which the golden rule rate has acted.
+func (d Drive) Exposure(t float64) float64 {
+	switch d.Kind {
+	case "step", "harmonic":
+		return math.Max(0, t-d.Start)
+	case "pulse":
+		// ∫ e^{-u²} du from -∞, in units of τ
+		return d.Duration * math.Sqrt(math.Pi) / 2 * (1 + math.Erf((t-d.Start)/d.Duration))
+	}
+	return 0
+}
+
+// Linear returns W(r) = slope × (r[axis] - centre), the perturbation of a
+// uniform field along an axis, such as an electric field acting on a
+// charge.
+func Linear(axis int, slope, centre float64) func(x, y, z float64) float64 {
+	return func(x, y, z float64) float64 {
+		return slope * ([3]float64{x, y, z}[axis] - centre)
+	}
+}
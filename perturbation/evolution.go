@@ -0,0 +1,296 @@
+package perturbation
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sync"
+
+	"hackathon/observables"
+	"hackathon/systems"
+)
+
+// Basis is a system whose eigenstates the perturbation couples, such as
+// an infinite, finite or double well or an oscillator.
+type Basis interface {
+	systems.System
+	// Energy returns the energy of eigenstate n.
+	Energy(n [3]int) float64
+	// Eigenstate returns the real spatial part of eigenstate n.
+	Eigenstate(n [3]int, x, y, z float64) float64
+}
+
+// Evolution is a superposition of a basis's levels evolving under a
+// perturbation. The coefficients are stepped by fourth order Runge-Kutta in
+// the interaction picture, where they only change through the coupling,
+// and the first-order amplitudes, which hold the coupling to the starting
+// state fixed, are integrated alongside.
+//
+// Evolution satisfies the systems.System contract. Evaluate steps forward
+// to the time asked for and starts again when asked for an earlier one,
+// so it suits viewers whose clock only runs forwards. It is safe for
+// concurrent use.
+type Evolution struct {
+	Basis    Basis
+	Levels   [][3]int    // quantum numbers of the levels followed
+	Energies []float64   // Eₙ of each level
+	Coupling [][]float64 // Wₘₙ = ⟨m|W|n⟩ between levels
+	Drive    Drive
+	Hbar     float64
+	Step     float64 // time step
+
+	mu      sync.Mutex
+	t       float64
+	initial []complex128 // coefficients at t = 0
+	c       []complex128 // coefficients at t
+	first   []complex128 // first-order coefficients at t
+}
+
+// NewEvolution works out the couplings of w between the levels and starts
+// the superposition given, normalised, at t = 0. Levels of the
+// superposition missing from levels are added to them.
+func NewEvolution(basis Basis, levels [][3]int, initial []systems.State, w func(x, y, z float64) float64, drive Drive, hbar float64) (*Evolution, error) {
+	if err := drive.Validate(); err != nil {
+		return nil, err
+	}
+	if len(initial) == 0 {
+		return nil, fmt.Errorf("perturbation: no starting state")
+	}
+	e := &Evolution{Basis: basis, Levels: append([][3]int(nil), levels...), Drive: drive, Hbar: hbar}
+	index := map[[3]int]int{}
+	for i, n := range e.Levels {
+		index[n] = i
+	}
+	for _, s := range initial {
+		if _, ok := index[s.N]; !ok {
+			index[s.N] = len(e.Levels)
+			e.Levels = append(e.Levels, s.N)
+		}
+	}
+
+	e.initial = make([]complex128, len(e.Levels))
+	norm := 0.0
+	for _, s := range initial {
+		e.initial[index[s.N]] += s.Amplitude
+	}
+	for _, a := range e.initial {
+		norm += real(a)*real(a) + imag(a)*imag(a)
+	}
+	if norm == 0 {
+		return nil, fmt.Errorf("perturbation: starting state has no amplitude")
+	}
+	for i := range e.initial {
+		e.initial[i] /= complex(math.Sqrt(norm), 0)
+	}
+
+	e.Energies = make([]float64, len(e.Levels))
+	for i, n := range e.Levels {
+		e.Energies[i] = basis.Energy(n)
+	}
+	e.Coupling = couplings(basis, e.Levels, w)
+
+	// Resolve the fastest of the Bohr frequencies, the drive and the
+	// couplings themselves in tens of steps
+	fastest := drive.Omega
+	if drive.Kind == "pulse" {
+		fastest = math.Max(fastest, 1/drive.Duration)
+	}
+	for m := range e.Levels {
+		for n := range e.Levels {
+			fastest = math.Max(fastest, math.Abs(e.Energies[m]-e.Energies[n])/hbar)
+			fastest = math.Max(fastest, math.Abs(e.Coupling[m][n])/hbar)
+		}
+	}
+	if fastest == 0 {
+		fastest = 1
+	}
+	e.Step = 2 * math.Pi / fastest / 40
+
+	e.reset()
+	return e, nil
+}
+
+// couplings integrates ⟨m|W|n⟩ for every pair of levels by Simpson
+// quadrature over the basis's bounds, evaluating each level once per node.
+func couplings(basis Basis, levels [][3]int, w func(x, y, z float64) float64) [][]float64 {
+	min, max := basis.Bounds()
+	intervals := 128
+	if max[2] > min[2] {
+		intervals = 48
+	}
+	q := observables.NewQuadrature(min, max, intervals)
+
+	n := len(levels)
+	pairs := n * (n + 1) / 2
+	phi := make([]float64, n)
+	sums := q.Integrate(pairs, func(p [3]float64, out []complex128) {
+		for i, l := range levels {
+			phi[i] = basis.Eigenstate(l, p[0], p[1], p[2])
+		}
+		v := w(p[0], p[1], p[2])
+		k := 0
+		for i := 0; i < n; i++ {
+			for j := i; j < n; j++ {
+				out[k] = complex(phi[i]*v*phi[j], 0)
+				k++
+			}
+		}
+	})
+
+	largest := 0.0
+	for _, s := range sums {
+		largest = math.Max(largest, math.Abs(real(s.Value)))
+	}
+	coupling := make([][]float64, n)
+	for i := range coupling {
+		coupling[i] = make([]float64, n)
+	}
+	k := 0
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			// What is left of a coupling symmetry forbids is rounding
+			if w := real(sums[k].Value); math.Abs(w) > 1e-10*largest {
+				coupling[i][j], coupling[j][i] = w, w
+			}
+			k++
+		}
+	}
+	return coupling
+}
+
+// Reset puts the superposition back as it started at t = 0.
+func (e *Evolution) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.reset()
+}
+
+func (e *Evolution) reset() {
+	e.c = append(e.c[:0], e.initial...)
+	e.first = append(e.first[:0], e.initial...)
+	e.t = 0
+}
+
+// Time returns how far the coefficients have been evolved.
+func (e *Evolution) Time() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.t
+}
+
+func (e *Evolution) advance(t float64) {
+	if t < e.t-e.Step/2 {
+		e.reset()
+	}
+	for e.t+e.Step <= t {
+		e.step(e.Step)
+	}
+	if t > e.t {
+		e.step(t - e.t)
+	}
+}
+
+// derivative returns dc/dt at t, from c for the full equations or from
+// the starting coefficients for the first-order ones.
+func (e *Evolution) derivative(t float64, c, out []complex128) {
+	f := e.Drive.At(t)
+	for m := range out {
+		out[m] = 0
+		if f == 0 {
+			continue
+		}
+		var sum complex128
+		for n, cn := range c {
+			if w := e.Coupling[m][n]; w != 0 {
+				sum += complex(w, 0) * cmplx.Exp(complex(0, (e.Energies[m]-e.Energies[n])*t/e.Hbar)) * cn
+			}
+		}
+		out[m] = complex(0, -f/e.Hbar) * sum
+	}
+}
+
+// step advances both sets of coefficients by dt.
+func (e *Evolution) step(dt float64) {
+	n := len(e.c)
+	k := make([][]complex128, 4)
+	for i := range k {
+		k[i] = make([]complex128, n)
+	}
+	tmp := make([]complex128, n)
+	stage := func(k []complex128, h complex128) {
+		for i := range tmp {
+			tmp[i] = e.c[i] + h*k[i]
+		}
+	}
+
+	h := complex(dt, 0)
+	e.derivative(e.t, e.c, k[0])
+	stage(k[0], h/2)
+	e.derivative(e.t+dt/2, tmp, k[1])
+	stage(k[1], h/2)
+	e.derivative(e.t+dt/2, tmp, k[2])
+	stage(k[2], h)
+	e.derivative(e.t+dt, tmp, k[3])
+	for i := range e.c {
+		e.c[i] += h / 6 * (k[0][i] + 2*k[1][i] + 2*k[2][i] + k[3][i])
+	}
+
+	// The first-order equations do not depend on their own coefficients,
+	// so the same four stages are Simpson's rule
+	e.derivative(e.t, e.initial, k[0])
+	e.derivative(e.t+dt/2, e.initial, k[1])
+	e.derivative(e.t+dt, e.initial, k[3])
+	for i := range e.first {
+		e.first[i] += h / 6 * (k[0][i] + 4*k[1][i] + k[3][i])
+	}
+	e.t += dt
+}
+
+// Amplitudes returns the interaction picture coefficients cₙ(t).
+func (e *Evolution) Amplitudes(t float64) []complex128 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.advance(t)
+	return append([]complex128(nil), e.c...)
+}
+
+// Populations returns |cₙ(t)|² for every level, from the full equations
+// and to first order. The first-order values need not sum to one.
+func (e *Evolution) Populations(t float64) (exact, first []float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.advance(t)
+	exact = make([]float64, len(e.c))
+	first = make([]float64, len(e.c))
+	for i := range e.c {
+		exact[i] = real(e.c[i])*real(e.c[i]) + imag(e.c[i])*imag(e.c[i])
+		first[i] = real(e.first[i])*real(e.first[i]) + imag(e.first[i])*imag(e.first[i])
+	}
+	return exact, first
+}
+
+// Evaluate returns ψ(x, y, z, t) = Σ cₙ(t) e^{-iEₙt/ħ} φₙ.
+func (e *Evolution) Evaluate(x, y, z, t float64) complex128 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.advance(t)
+	var waveFunction complex128
+	for i, n := range e.Levels {
+		if e.c[i] == 0 {
+			continue
+		}
+		phase := -e.Energies[i] * t / e.Hbar
+		waveFunction += e.c[i] * complex(e.Basis.Eigenstate(n, x, y, z), 0) * cmplx.Exp(complex(0, phase))
+	}
+	return waveFunction
+}
+
+// Potential returns the unperturbed potential of the basis.
+func (e *Evolution) Potential(x, y, z float64) float64 {
+	return e.Basis.Potential(x, y, z)
+}
+
+// Bounds returns the bounds of the basis.
+func (e *Evolution) Bounds() (min, max [3]float64) {
+	return e.Basis.Bounds()
+}
@@ -0,0 +1,107 @@
+package perturbation
+
+import "math"
+
+// Resonant returns the level the drive couples to from level from whose
+// Bohr frequency |ωₜₒ,from| lies closest to the drive's, and the detuning
+// Δ = ω - |ωₜₒ,from|. A step has no frequency, so the level nearest in
+// energy is returned. It returns -1 when no other level is coupled.
+func (e *Evolution) Resonant(from int) (to int, detuning float64) {
+	omega := e.Drive.Omega
+	if e.Drive.Kind == "step" {
+		omega = 0
+	}
+	to = -1
+	for n := range e.Levels {
+		if n == from || e.Coupling[n][from] == 0 {
+			continue
+		}
+		d := omega - e.bohr(n, from)
+		if to < 0 || math.Abs(d) < math.Abs(detuning) {
+			to, detuning = n, d
+		}
+	}
+	return to, detuning
+}
+
+// bohr returns |ωₘₙ| = |Eₘ - Eₙ|/ħ.
+func (e *Evolution) bohr(m, n int) float64 {
+	return math.Abs(e.Energies[m]-e.Energies[n]) / e.Hbar
+}
+
+// RabiFrequency returns Ω = |Wₜₒ,from|/ħ, the frequency at which a
+// resonant harmonic drive cycles population between two levels.
+func (e *Evolution) RabiFrequency(from, to int) float64 {
+	return math.Abs(e.Coupling[to][from]) / e.Hbar
+}
+
+// Rabi returns the population of level to at time t predicted for two
+// levels alone, started in level from, under a harmonic drive W cos ωt in
+// the rotating wave approximation:
+//
+//	P = Ω²/(Ω² + Δ²) sin²(√(Ω² + Δ²) t/2),
+//
+// with t counted from when the drive switches on. The other levels and the
+// counter-rotating half of the drive shift it by about (Ω/ω)².
+func (e *Evolution) Rabi(from, to int, t float64) float64 {
+	if e.Drive.Kind != "harmonic" || t <= e.Drive.Start {
+		return 0
+	}
+	omega := e.RabiFrequency(from, to)
+	detuning := e.Drive.Omega - e.bohr(to, from)
+	general := math.Hypot(omega, detuning)
+	if general == 0 {
+		return 0
+	}
+	s := math.Sin(general * (t - e.Drive.Start) / 2)
+	return omega * omega / (general * general) * s * s
+}
+
+// Density returns the density of levels ρ = 1/δE about level n, from the
+// spacing to its neighbours in energy among the levels followed, or zero
+// when it has none.
+func (e *Evolution) Density(n int) float64 {
+	below, above := math.Inf(-1), math.Inf(1)
+	for _, energy := range e.Energies {
+		switch {
+		case energy < e.Energies[n]:
+			below = math.Max(below, energy)
+		case energy > e.Energies[n]:
+			above = math.Min(above, energy)
+		}
+	}
+	switch {
+	case !math.IsInf(below, 0) && !math.IsInf(above, 0):
+		return 2 / (above - below)
+	case !math.IsInf(below, 0):
+		return 1 / (e.Energies[n] - below)
+	case !math.IsInf(above, 0):
+		return 1 / (above - e.Energies[n])
+	}
+	return 0
+}
+
+// GoldenRule returns Fermi's golden rule rate from level from to level to,
+// treating the levels about to as a continuum of density ρ:
+//
+//	Γ = (2π/ħ) |Wₜₒ,from|² ρ for a step,
+//	Γ = (π/2ħ) |Wₜₒ,from|² ρ for a harmonic drive or pulse,
+//
+// where half the amplitude of cos ωt drives the absorption or emission.
+// Multiplied by Drive.Exposure it is the population the rule predicts,
+// which holds while that stays small and t is short of the Heisenberg
+// time 2πħρ, before the drive resolves the levels apart.
+func (e *Evolution) GoldenRule(from, to int) float64 {
+	w := e.Coupling[to][from]
+	rate := 2 * math.Pi / e.Hbar * w * w * e.Density(to)
+	if e.Drive.Kind != "step" {
+		rate /= 4
+	}
+	return rate
+}
+
+// Heisenberg returns 2πħρ about level n, the time a drive takes to tell
+// it from its neighbours.
+func (e *Evolution) Heisenberg(n int) float64 {
+	return 2 * math.Pi * e.Hbar * e.Density(n)
+}
@@ -0,0 +1,83 @@
+// Package sampling generates the point sets the viewers colour and the
+// exporters write out. Points are [x, y, z] slices, the layout the viewers'
+// generateRandomCoords has always produced.
+package sampling
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+
+	"hackathon/systems"
+)
+
+// Uniform scatters n points uniformly inside the box [min, max].
+func Uniform(n int, min, max [3]float64, seed int64) [][]float64 {
+	result := make([][]float64, n)
+	r := rand.New(rand.NewSource(seed))
+	for i := 0; i < n; i++ {
+		result[i] = make([]float64, 3)
+		for d := 0; d < 3; d++ {
+			result[i][d] = min[d] + r.Float64()*(max[d]-min[d])
+		}
+	}
+	return result
+}
+
+// Grid places points on a regular lattice with the given spacing. Axes where
+// min equals max contribute a single layer.
+func Grid(min, max [3]float64, step float64) [][]float64 {
+	var counts [3]int
+	for d := 0; d < 3; d++ {
+		counts[d] = int(math.Floor((max[d]-min[d])/step+1e-9)) + 1
+	}
+	result := make([][]float64, 0, counts[0]*counts[1]*counts[2])
+	for i := 0; i < counts[0]; i++ {
+		for j := 0; j < counts[1]; j++ {
+			for k := 0; k < counts[2]; k++ {
+				result = append(result, []float64{
+					min[0] + float64(i)*step,
+					min[1] + float64(j)*step,
+					min[2] + float64(k)*step,
+				})
+			}
+		}
+	}
+	return result
+}
+
+// Density draws n points distributed according to |ψ(x, y, z, t)|² inside the
+// box [min, max] by rejection sampling, so dense regions of the point cloud
+// are where the particle is likely to be found.
+func Density(sys systems.Evaluator, t float64, n int, min, max [3]float64, seed int64) [][]float64 {
+	r := rand.New(rand.NewSource(seed))
+
+	// Estimate the peak density from a uniform pilot sample
+	peak := 0.0
+	for _, p := range Uniform(4*n+1000, min, max, seed+1) {
+		if d := probability(sys, p, t); d > peak {
+			peak = d
+		}
+	}
+	if peak == 0 {
+		return Uniform(n, min, max, seed)
+	}
+	peak *= 1.2
+
+	result := make([][]float64, 0, n)
+	for len(result) < n {
+		p := make([]float64, 3)
+		for d := 0; d < 3; d++ {
+			p[d] = min[d] + r.Float64()*(max[d]-min[d])
+		}
+		if r.Float64()*peak < probability(sys, p, t) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func probability(sys systems.Evaluator, p []float64, t float64) float64 {
+	v := cmplx.Abs(sys.Evaluate(p[0], p[1], p[2], t))
+	return v * v
+}
@@ -0,0 +1,470 @@
+package scattering
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sync"
+
+	"gonum.org/v1/gonum/dsp/fourier"
+)
+
+// Packet is a Gaussian wavepacket sent at the barrier.
+type Packet struct {
+	Energy float64    // mean kinetic energy ħ²k₀²/2m
+	Spread float64    // standard deviation σ of |ψ|² in position
+	Start  [2]float64 // centre at t = 0
+	Angle  float64    // direction of travel from the x axis in 2D, in radians
+}
+
+// Grid is the box a packet is evolved in. A grid with one cell or none
+// along y is 1D.
+type Grid struct {
+	Min, Max [2]float64
+	Cells    [2]int
+}
+
+// Fit returns the grid with any axis given no cells filled in with the
+// smallest power of two fine enough for the packet, with some room to
+// spare. Along x it also puts several cells across the thinnest region of
+// the profile and resolves the decay under its highest value.
+func (g Grid) Fit(prof Profile, p Packet, hbar, mass float64, dims int) Grid {
+	kmax := math.Sqrt(2*mass*math.Max(p.Energy, math.Abs(prof.Max())))/hbar + 2/p.Spread
+	thinnest := math.Inf(1)
+	for i := 1; i < len(prof.Edges); i++ {
+		thinnest = math.Min(thinnest, prof.Edges[i]-prof.Edges[i-1])
+	}
+	for d := 0; d < dims; d++ {
+		if g.Cells[d] != 0 {
+			continue
+		}
+		need := 1.8 * kmax * (g.Max[d] - g.Min[d]) / math.Pi
+		if d == 0 {
+			need = math.Max(need, 8*(g.Max[d]-g.Min[d])/thinnest)
+		}
+		g.Cells[d] = 64
+		for float64(g.Cells[d]) < need {
+			g.Cells[d] *= 2
+		}
+	}
+	return g
+}
+
+// absorbing is the fraction of the grid at each edge that soaks up the
+// packet, so nothing wraps round the periodic Fourier transforms.
+const absorbing = 0.1
+
+// Simulation evolves a packet through a profile by the split-operator
+// method: half a step of the potential, a full step of the kinetic energy
+// applied in Fourier space, and another half step of the potential. This is
+// unitary, so the norm only falls where layers at the edges of the grid
+// absorb the packet; what they take is booked as transmitted or reflected
+// by which side of the barrier it left through.
+//
+// Simulation satisfies the systems.System contract. Evaluate steps forward
+// to the time asked for and starts again from the packet when asked for an
+// earlier one, so it suits viewers whose clock only runs forwards. It is
+// safe for concurrent use.
+type Simulation struct {
+	Profile Profile
+	Packet  Packet
+	Grid    Grid
+	Hbar    float64
+	Mass    float64
+	Step    float64 // time step
+
+	mu       sync.Mutex
+	t        float64
+	psi      []complex128
+	buf      []complex128
+	kinetic  []complex128 // e^{-iħk²Δt/2m} for each Fourier coefficient
+	half     []complex128 // e^{-iVΔt/2ħ} for each cell
+	v        []float64    // mean of the profile over each cell
+	mask     []float64    // fraction of each cell kept per step by the absorbing layers
+	absorbed [2]float64   // probability absorbed left and right of the barrier
+	fft      [2]*fourier.CmplxFFT
+	dx       [2]float64
+}
+
+// NewSimulation prepares a packet on a grid. It fails if the grid is too
+// coarse to carry the packet's momentum or too small to hold it.
+func NewSimulation(p Profile, packet Packet, grid Grid, hbar, mass float64) (*Simulation, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if packet.Energy <= 0 || packet.Spread <= 0 {
+		return nil, fmt.Errorf("scattering: packet energy and spread must be positive")
+	}
+	if grid.Cells[1] < 1 {
+		grid.Cells[1] = 1
+	}
+	s := &Simulation{Profile: p, Packet: packet, Grid: grid, Hbar: hbar, Mass: mass}
+	for d := 0; d < s.dims(); d++ {
+		if grid.Cells[d] < 8 || grid.Max[d] <= grid.Min[d] {
+			return nil, fmt.Errorf("scattering: grid needs at least 8 cells and max above min on each axis")
+		}
+		s.dx[d] = (grid.Max[d] - grid.Min[d]) / float64(grid.Cells[d])
+		s.fft[d] = fourier.NewCmplxFFT(grid.Cells[d])
+	}
+
+	// The packet's momentum spread must fit well inside the grid's
+	// Nyquist wavenumber, and its body inside the absorbing layers
+	k0 := math.Sqrt(2*mass*packet.Energy) / hbar
+	if kmax := k0 + 4/(2*packet.Spread); kmax > math.Pi/s.dx[0]/1.5 {
+		return nil, fmt.Errorf("scattering: grid spacing %.3g is too coarse for a packet of energy %v, use at least %d cells", s.dx[0], packet.Energy, int(1.5*kmax*(grid.Max[0]-grid.Min[0])/math.Pi)+1)
+	}
+	layer := absorbing * (grid.Max[0] - grid.Min[0])
+	if packet.Start[0]-3*packet.Spread < grid.Min[0]+layer {
+		return nil, fmt.Errorf("scattering: packet starting at x = %v reaches into the absorbing layer at the left of the grid", packet.Start[0])
+	}
+
+	// Resolve the fastest phase, of the packet or the barrier, in tens of steps
+	fastest := math.Max(packet.Energy, math.Abs(p.Max()))
+	fastest = math.Max(fastest, math.Abs(p.Values[0]))
+	s.Step = 0.05 * hbar / fastest
+
+	s.prepare()
+	s.Reset()
+	return s, nil
+}
+
+func (s *Simulation) dims() int {
+	if s.Grid.Cells[1] > 1 {
+		return 2
+	}
+	return 1
+}
+
+// prepare builds the per-cell factors applied each step.
+func (s *Simulation) prepare() {
+	nx, ny := s.Grid.Cells[0], s.Grid.Cells[1]
+	n := nx * ny
+	s.psi = make([]complex128, n)
+	s.buf = make([]complex128, max(nx, ny))
+	s.kinetic = make([]complex128, n)
+	s.half = make([]complex128, n)
+	s.v = make([]float64, n)
+	s.mask = make([]float64, n)
+
+	// Absorbing layers take out all but a trace of the packet in one
+	// crossing, so nothing wraps round the grid to the far side
+	v := math.Sqrt(2 * s.Packet.Energy / s.Mass)
+	strength := [2]float64{}
+	for d := 0; d < s.dims(); d++ {
+		strength[d] = 20 * v / (absorbing * (s.Grid.Max[d] - s.Grid.Min[d]))
+	}
+
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			c := j*nx + i
+			x, y := s.cell(i, j)
+			s.v[c] = s.Profile.Mean(x-s.dx[0]/2, x+s.dx[0]/2)
+			s.half[c] = cmplx.Exp(complex(0, -s.v[c]*s.Step/(2*s.Hbar)))
+
+			k := s.wavevector(i, j)
+			k2 := k[0]*k[0] + k[1]*k[1]
+			s.kinetic[c] = cmplx.Exp(complex(0, -s.Hbar*k2*s.Step/(2*s.Mass)))
+
+			decay := strength[0] * depth(x, s.Grid.Min[0], s.Grid.Max[0])
+			if s.dims() == 2 {
+				decay += strength[1] * depth(y, s.Grid.Min[1], s.Grid.Max[1])
+			}
+			s.mask[c] = math.Exp(-decay * s.Step)
+		}
+	}
+}
+
+// wavevector returns the wavevector of Fourier coefficient (i, j).
+func (s *Simulation) wavevector(i, j int) (k [2]float64) {
+	k[0] = 2 * math.Pi * s.fft[0].Freq(i) / s.dx[0]
+	if s.dims() == 2 {
+		k[1] = 2 * math.Pi * s.fft[1].Freq(j) / s.dx[1]
+	}
+	return k
+}
+
+// depth returns how far u is into an absorbing layer of [min, max], from 0
+// at its inner edge to 1 at the edge of the grid, squared so the layer
+// switches on smoothly and reflects little.
+func depth(u, min, max float64) float64 {
+	layer := absorbing * (max - min)
+	s := 0.0
+	if u < min+layer {
+		s = (min + layer - u) / layer
+	} else if u > max-layer {
+		s = (u - (max - layer)) / layer
+	}
+	return s * s
+}
+
+// cell returns the position of cell (i, j).
+func (s *Simulation) cell(i, j int) (x, y float64) {
+	x = s.Grid.Min[0] + (float64(i)+0.5)*s.dx[0]
+	if s.dims() == 2 {
+		y = s.Grid.Min[1] + (float64(j)+0.5)*s.dx[1]
+	}
+	return x, y
+}
+
+// Reset puts the packet back at its start at t = 0.
+func (s *Simulation) Reset() {
+	k0 := math.Sqrt(2*s.Mass*s.Packet.Energy) / s.Hbar
+	kx, ky := k0*math.Cos(s.Packet.Angle), k0*math.Sin(s.Packet.Angle)
+	sigma := s.Packet.Spread
+	norm := 0.0
+	for j := 0; j < s.Grid.Cells[1]; j++ {
+		for i := 0; i < s.Grid.Cells[0]; i++ {
+			x, y := s.cell(i, j)
+			u, w := x-s.Packet.Start[0], 0.0
+			phase := kx * u
+			if s.dims() == 2 {
+				w = y - s.Packet.Start[1]
+				phase += ky * w
+			}
+			psi := complex(math.Exp(-(u*u+w*w)/(4*sigma*sigma)), 0) * cmplx.Exp(complex(0, phase))
+			s.psi[j*s.Grid.Cells[0]+i] = psi
+			norm += real(psi)*real(psi) + imag(psi)*imag(psi)
+		}
+	}
+	// Normalise so Σ|ψ|² ΔV = 1 on the grid itself
+	scale := complex(1/math.Sqrt(norm*s.volume()), 0)
+	for c := range s.psi {
+		s.psi[c] *= scale
+	}
+	s.t = 0
+	s.absorbed = [2]float64{}
+}
+
+// volume returns the length or area of one cell.
+func (s *Simulation) volume() float64 {
+	if s.dims() == 2 {
+		return s.dx[0] * s.dx[1]
+	}
+	return s.dx[0]
+}
+
+// Time returns how far the packet has been evolved.
+func (s *Simulation) Time() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.t
+}
+
+// Advance evolves the packet until t, or restarts it if t is earlier than
+// the time it has reached.
+func (s *Simulation) Advance(t float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(t)
+}
+
+func (s *Simulation) advance(t float64) {
+	if t < s.t-s.Step/2 {
+		s.Reset()
+	}
+	for s.t+s.Step/2 <= t {
+		s.step()
+	}
+}
+
+// step advances the packet by one time step.
+func (s *Simulation) step() {
+	for c := range s.psi {
+		s.psi[c] *= s.half[c]
+	}
+	s.transform(s.psi, false)
+	for c := range s.psi {
+		s.psi[c] *= s.kinetic[c]
+	}
+	s.transform(s.psi, true)
+	start, end := s.Profile.Span()
+	nx := s.Grid.Cells[0]
+	for c := range s.psi {
+		s.psi[c] *= s.half[c]
+		if s.mask[c] == 1 {
+			continue
+		}
+		// Book what the absorbing layer takes by the side it left through
+		before := real(s.psi[c])*real(s.psi[c]) + imag(s.psi[c])*imag(s.psi[c])
+		s.psi[c] *= complex(s.mask[c], 0)
+		lost := before * (1 - s.mask[c]*s.mask[c]) * s.volume()
+		x, _ := s.cell(c%nx, c/nx)
+		if x >= end {
+			s.absorbed[1] += lost
+		} else if x <= start {
+			s.absorbed[0] += lost
+		}
+	}
+	s.t += s.Step
+}
+
+// transform applies the Fourier transform along each axis of a grid of
+// values in place, or its normalised inverse.
+func (s *Simulation) transform(psi []complex128, inverse bool) {
+	nx, ny := s.Grid.Cells[0], s.Grid.Cells[1]
+	apply := func(fft *fourier.CmplxFFT, data []complex128) {
+		if inverse {
+			fft.Sequence(data, data)
+			scale := complex(1/float64(len(data)), 0)
+			for i := range data {
+				data[i] *= scale
+			}
+		} else {
+			fft.Coefficients(data, data)
+		}
+	}
+	for j := 0; j < ny; j++ {
+		apply(s.fft[0], psi[j*nx:(j+1)*nx])
+	}
+	if s.dims() == 1 {
+		return
+	}
+	col := s.buf[:ny]
+	for i := 0; i < nx; i++ {
+		for j := 0; j < ny; j++ {
+			col[j] = psi[j*nx+i]
+		}
+		apply(s.fft[1], col)
+		for j := 0; j < ny; j++ {
+			psi[j*nx+i] = col[j]
+		}
+	}
+}
+
+// Measure returns the probability found, or already absorbed, right of the
+// barrier and left of it. Once the packet has left the barrier these are
+// the packet's transmission and reflection probabilities, which average the
+// plane wave coefficients over the packet's spread of energies.
+func (s *Simulation) Measure() (transmitted, reflected float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	start, end := s.Profile.Span()
+	nx := s.Grid.Cells[0]
+	for c, psi := range s.psi {
+		x, _ := s.cell(c%nx, c/nx)
+		p := (real(psi)*real(psi) + imag(psi)*imag(psi)) * s.volume()
+		if x >= end {
+			transmitted += p
+		} else if x <= start {
+			reflected += p
+		}
+	}
+	return transmitted + s.absorbed[1], reflected + s.absorbed[0]
+}
+
+// Moments returns ⟨p⟩, ⟨p²⟩ and ⟨H⟩ of the packet left on the grid at t.
+// The momenta are read off its Fourier coefficients, which the interpolated
+// ψ Evaluate returns is too coarse to differentiate, and the energy adds
+// the potential averaged over each cell, as the steps apply it.
+func (s *Simulation) Moments(t float64) (p, p2 [3]float64, energy float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(t)
+
+	norm, potential := 0.0, 0.0
+	for c, psi := range s.psi {
+		density := real(psi)*real(psi) + imag(psi)*imag(psi)
+		norm += density
+		potential += s.v[c] * density
+	}
+	if norm == 0 {
+		return p, p2, 0
+	}
+
+	coefficients := append([]complex128(nil), s.psi...)
+	s.transform(coefficients, false)
+	nx := s.Grid.Cells[0]
+	total := 0.0
+	for c, a := range coefficients {
+		weight := real(a)*real(a) + imag(a)*imag(a)
+		total += weight
+		k := s.wavevector(c%nx, c/nx)
+		for d := 0; d < s.dims(); d++ {
+			p[d] += s.Hbar * k[d] * weight
+			p2[d] += s.Hbar * s.Hbar * k[d] * k[d] * weight
+		}
+	}
+	kinetic := 0.0
+	for d := 0; d < s.dims(); d++ {
+		p[d] /= total
+		p2[d] /= total
+		kinetic += p2[d] / (2 * s.Mass)
+	}
+	return p, p2, kinetic + potential/norm
+}
+
+// Duration returns how long the centre of the packet takes, at its group
+// velocity, to cross from its start to the right hand edge of the grid.
+// Some time after that both halves have left the barrier.
+func (s *Simulation) Duration() float64 {
+	v := math.Sqrt(2*s.Packet.Energy/s.Mass) * math.Cos(s.Packet.Angle)
+	return (s.Grid.Max[0] - s.Packet.Start[0]) / math.Max(v, 1e-300)
+}
+
+// Arrival returns when the slow edge of the packet, three momentum
+// spreads below its mean, has reached the far side of the barrier. Before
+// then the part of the packet still on its way in counts as reflected.
+func (s *Simulation) Arrival() float64 {
+	_, end := s.Profile.Span()
+	k0 := math.Sqrt(2*s.Mass*s.Packet.Energy) / s.Hbar
+	slow := (k0 - 3/(2*s.Packet.Spread)) * math.Cos(s.Packet.Angle)
+	slow = math.Max(slow, k0*math.Cos(s.Packet.Angle)/4)
+	return (end - s.Packet.Start[0] + 3*s.Packet.Spread) / (s.Hbar * slow / s.Mass)
+}
+
+// Run evolves the packet past its arrival at the barrier and on until no
+// more than a fraction tolerance of it is left over the barrier, or until
+// limit, and returns the time it stopped.
+func (s *Simulation) Run(tolerance, limit float64) float64 {
+	s.Advance(math.Min(s.Arrival(), limit))
+	for {
+		transmitted, reflected := s.Measure()
+		if t := s.Time(); 1-transmitted-reflected <= tolerance || t >= limit {
+			return t
+		}
+		s.Advance(s.Time() + 20*s.Step)
+	}
+}
+
+// Evaluate returns ψ(x, y, t), interpolated between the grid's cells and
+// zero outside it.
+func (s *Simulation) Evaluate(x, y, z, t float64) complex128 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(t)
+
+	nx, ny := s.Grid.Cells[0], s.Grid.Cells[1]
+	fx := (x-s.Grid.Min[0])/s.dx[0] - 0.5
+	i := int(math.Floor(fx))
+	if i < 0 || i+1 >= nx {
+		return 0
+	}
+	ax := complex(fx-float64(i), 0)
+	if s.dims() == 1 {
+		return (1-ax)*s.psi[i] + ax*s.psi[i+1]
+	}
+	fy := (y-s.Grid.Min[1])/s.dx[1] - 0.5
+	j := int(math.Floor(fy))
+	if j < 0 || j+1 >= ny {
+		return 0
+	}
+	ay := complex(fy-float64(j), 0)
+	row := func(j int) complex128 {
+		return (1-ax)*s.psi[j*nx+i] + ax*s.psi[j*nx+i+1]
+	}
+	return (1-ay)*row(j) + ay*row(j+1)
+}
+
+// Potential returns the profile at x.
+func (s *Simulation) Potential(x, y, z float64) float64 {
+	return s.Profile.At(x)
+}
+
+// Bounds returns the grid.
+func (s *Simulation) Bounds() (min, max [3]float64) {
+	min[0], max[0] = s.Grid.Min[0], s.Grid.Max[0]
+	if s.dims() == 2 {
+		min[1], max[1] = s.Grid.Min[1], s.Grid.Max[1]
+	}
+	return min, max
+}
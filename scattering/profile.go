@@ -0,0 +1,101 @@
+// Package scattering sends particles at one dimensional potential barriers:
+// steps, rectangular barriers and double barriers. It finds how much is
+// transmitted and reflected two ways, exactly for plane waves from the
+// transfer matrix of the piecewise constant potential, and by evolving a
+// wavepacket in time on a grid, in 1D or in 2D with the barrier running
+// along y. Plane waves and packets both satisfy the systems.System contract,
+// so the viewers and exporters can draw them.
+package scattering
+
+import (
+	"fmt"
+	"math"
+)
+
+// Profile is a potential that is constant between edges: Values[0] left of
+// Edges[0], Values[i] between Edges[i-1] and Edges[i], and the last value
+// right of the last edge.
+type Profile struct {
+	Edges  []float64
+	Values []float64
+}
+
+// Step rises from 0 to height at x = at.
+func Step(at, height float64) Profile {
+	return Profile{Edges: []float64{at}, Values: []float64{0, height}}
+}
+
+// Barrier is a rectangular barrier of the given height and thickness whose
+// left face is at x = at.
+func Barrier(at, thickness, height float64) Profile {
+	return Profile{Edges: []float64{at, at + thickness}, Values: []float64{0, height, 0}}
+}
+
+// DoubleBarrier is two barriers of the same height and thickness separated
+// by a gap, the left face of the first at x = at. Between them lie
+// quasi-bound states that let it transmit fully at resonant energies.
+func DoubleBarrier(at, thickness, gap, height float64) Profile {
+	return Profile{
+		Edges:  []float64{at, at + thickness, at + thickness + gap, at + 2*thickness + gap},
+		Values: []float64{0, height, 0, height, 0},
+	}
+}
+
+// Validate reports a profile whose edges are out of order or whose values
+// do not fill the gaps between them.
+func (p Profile) Validate() error {
+	if len(p.Values) != len(p.Edges)+1 {
+		return fmt.Errorf("scattering: %d edges need %d values, got %d", len(p.Edges), len(p.Edges)+1, len(p.Values))
+	}
+	for i := 1; i < len(p.Edges); i++ {
+		if p.Edges[i] <= p.Edges[i-1] {
+			return fmt.Errorf("scattering: edges must increase, %v follows %v", p.Edges[i], p.Edges[i-1])
+		}
+	}
+	return nil
+}
+
+// At returns the potential at x.
+func (p Profile) At(x float64) float64 {
+	i := 0
+	for i < len(p.Edges) && x >= p.Edges[i] {
+		i++
+	}
+	return p.Values[i]
+}
+
+// Mean returns the average of the potential over [a, b], which a grid
+// uses for its cells so a barrier keeps its thickness between grid points.
+func (p Profile) Mean(a, b float64) float64 {
+	sum := 0.0
+	lo := a
+	for i, v := range p.Values {
+		hi := b
+		if i < len(p.Edges) {
+			hi = math.Min(b, p.Edges[i])
+		}
+		if hi > lo {
+			sum += v * (hi - lo)
+			lo = hi
+		}
+	}
+	return sum / (b - a)
+}
+
+// Span returns the first and last edge, between which the potential is
+// not that of either side.
+func (p Profile) Span() (start, end float64) {
+	if len(p.Edges) == 0 {
+		return 0, 0
+	}
+	return p.Edges[0], p.Edges[len(p.Edges)-1]
+}
+
+// Max returns the highest value of the potential.
+func (p Profile) Max() float64 {
+	v := math.Inf(-1)
+	for _, x := range p.Values {
+		v = math.Max(v, x)
+	}
+	return v
+}
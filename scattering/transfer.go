@@ -0,0 +1,141 @@
+package scattering
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// PlaneWave is the stationary state of energy E sent at a profile from the
+// left: e^{ikx} plus a reflected wave on the left, a transmitted wave on
+// the right, and waves running both ways, or growing and decaying, in
+// between. It is not normalisable; the incident wave has unit amplitude.
+type PlaneWave struct {
+	Profile Profile
+	Energy  float64
+	Hbar    float64
+	Mass    float64
+
+	k      []complex128 // wavenumber in each region, imaginary where E < V
+	a, b   []complex128 // right and left moving amplitudes in each region
+	origin []float64    // where each region's phases are measured from
+}
+
+// NewPlaneWave solves for the plane wave of the given energy by the
+// transfer matrix method. Each region holds a e^{ik(x-x₀)} + b e^{-ik(x-x₀)},
+// and continuity of ψ and ψ' at each edge carries the amplitudes from one
+// region to the next. Starting from a purely transmitted wave on the right
+// and working left, the incident amplitude comes out last and everything
+// is scaled by it. Across a region where the wave decays its amplitude
+// grows by e^{κw}, which overflows for thick or high barriers, so the
+// regions already solved are rescaled as each is added: the transmitted
+// wave then fades to nothing rather than the incident one growing without
+// bound. The energy must exceed the potential on the left.
+func NewPlaneWave(p Profile, energy, hbar, mass float64) (*PlaneWave, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if energy <= p.Values[0] {
+		return nil, fmt.Errorf("scattering: energy %v does not exceed the potential %v the wave comes in on", energy, p.Values[0])
+	}
+	// At E = V the region's wavenumber vanishes and the matching below
+	// divides by it; an energy a hair away gives the same coefficients
+	for _, v := range p.Values {
+		if energy == v {
+			energy = math.Nextafter(energy, math.Inf(1))
+		}
+	}
+
+	n := len(p.Values)
+	w := &PlaneWave{
+		Profile: p, Energy: energy, Hbar: hbar, Mass: mass,
+		k: make([]complex128, n), a: make([]complex128, n), b: make([]complex128, n),
+		origin: make([]float64, n),
+	}
+	for i, v := range p.Values {
+		w.k[i] = cmplx.Sqrt(complex(2*mass*(energy-v), 0)) / complex(hbar, 0)
+		if i > 0 {
+			w.origin[i] = p.Edges[i-1]
+		} else if len(p.Edges) > 0 {
+			w.origin[i] = p.Edges[0]
+		}
+	}
+
+	// Transmitted wave only on the right, decaying if E is below the last value
+	w.a[n-1] = 1
+	for i := n - 2; i >= 0; i-- {
+		// ψ and ψ' at the edge, from the region to its right
+		psi := w.a[i+1] + w.b[i+1]
+		dpsi := complex(0, 1) * w.k[i+1] * (w.a[i+1] - w.b[i+1])
+		edge := p.Edges[i] - w.origin[i]
+		ik := complex(0, 1) * w.k[i]
+		phase := cmplx.Exp(ik * complex(edge, 0))
+		a := (psi + dpsi/ik) / 2
+		b := (psi - dpsi/ik) / 2 * phase * phase
+
+		// a e^{ik(x-x₀)} carries 1/phase, up to e^{κw}; keep it out and
+		// scale this region and those to its right to the larger amplitude
+		scale := math.Max(cmplx.Abs(a), cmplx.Abs(b))
+		w.a[i], w.b[i] = a/complex(scale, 0), b/complex(scale, 0)
+		right := phase / complex(scale, 0)
+		for j := i + 1; j < n; j++ {
+			w.a[j] *= right
+			w.b[j] *= right
+		}
+	}
+	scale := w.a[0]
+	for i := range w.a {
+		w.a[i] /= scale
+		w.b[i] /= scale
+	}
+	return w, nil
+}
+
+// Coefficients returns the transmitted and reflected fractions of the
+// incident probability current. T is zero when the wave cannot propagate
+// on the right.
+func (w *PlaneWave) Coefficients() (transmitted, reflected float64) {
+	n := len(w.k)
+	reflected = cmplx.Abs(w.b[0]) * cmplx.Abs(w.b[0])
+	if real(w.k[n-1]) > 0 && imag(w.k[n-1]) == 0 {
+		transmitted = real(w.k[n-1]) / real(w.k[0]) * cmplx.Abs(w.a[n-1]) * cmplx.Abs(w.a[n-1])
+	}
+	return transmitted, reflected
+}
+
+// Coefficients returns the transmission and reflection probabilities of a
+// plane wave of the given energy, see PlaneWave.Coefficients.
+func Coefficients(p Profile, energy, hbar, mass float64) (transmitted, reflected float64, err error) {
+	w, err := NewPlaneWave(p, energy, hbar, mass)
+	if err != nil {
+		return 0, 0, err
+	}
+	transmitted, reflected = w.Coefficients()
+	return transmitted, reflected, nil
+}
+
+// Evaluate returns ψ(x, t), the same for every y and z.
+func (w *PlaneWave) Evaluate(x, y, z, t float64) complex128 {
+	i := 0
+	for i < len(w.Profile.Edges) && x >= w.Profile.Edges[i] {
+		i++
+	}
+	ik := complex(0, 1) * w.k[i]
+	u := complex(x-w.origin[i], 0)
+	psi := w.a[i]*cmplx.Exp(ik*u) + w.b[i]*cmplx.Exp(-ik*u)
+	return psi * cmplx.Exp(complex(0, -w.Energy*t/w.Hbar))
+}
+
+// Potential returns the profile at x.
+func (w *PlaneWave) Potential(x, y, z float64) float64 {
+	return w.Profile.At(x)
+}
+
+// Bounds returns the profile padded by three incident wavelengths on
+// either side.
+func (w *PlaneWave) Bounds() (min, max [3]float64) {
+	start, end := w.Profile.Span()
+	pad := 3 * 2 * math.Pi / real(w.k[0])
+	min[0], max[0] = start-pad, end+pad
+	return min, max
+}
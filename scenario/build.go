@@ -0,0 +1,266 @@
+package scenario
+
+import (
+	"fmt"
+	"math"
+
+	"hackathon/axes"
+	"hackathon/colormap"
+	"hackathon/observables"
+	"hackathon/perturbation"
+	"hackathon/sampling"
+	"hackathon/scattering"
+	"hackathon/systems"
+)
+
+// Constants returns ħ and the particle mass in the scenario's unit system.
+func (s *System) Constants() (hbar, mass float64) {
+	hbar, mass = 1, 1
+	if s.Units == "si" {
+		hbar, mass = systems.HbarSI, systems.ElectronMass
+	}
+	if s.Hbar != 0 {
+		hbar = s.Hbar
+	}
+	if s.Mass != 0 {
+		mass = s.Mass
+	}
+	return hbar, mass
+}
+
+// Coulomb returns e²/4πε₀ in the scenario's unit system, 1 in natural units.
+func (s *System) Coulomb() float64 {
+	if s.Units == "si" {
+		return systems.CoulombSI
+	}
+	return 1
+}
+
+// Build constructs the system the scenario describes. A driven system is a
+// *perturbation.Evolution over the levels of the well or oscillator.
+func (s *Scenario) Build() (systems.System, error) {
+	d := s.System.Drive
+	if d.Kind == "none" {
+		return s.build(s.System.States())
+	}
+	// The basis holds the coupled levels too, at zero amplitude, so it
+	// solves for them and its bounds take them in
+	states := s.System.States()
+	for _, n := range d.Levels {
+		states = append(states, systems.State{N: quantum(n)})
+	}
+	sys, err := s.build(states)
+	if err != nil {
+		return nil, err
+	}
+	basis, ok := sys.(perturbation.Basis)
+	if !ok {
+		return nil, fmt.Errorf("system: a %s cannot be driven", s.System.Kind)
+	}
+	var levels [][3]int
+	for _, n := range d.Levels {
+		levels = append(levels, quantum(n))
+	}
+	min, max := basis.Bounds()
+	field := perturbation.Linear(d.Axis, d.Strength, (min[d.Axis]+max[d.Axis])/2)
+	hbar, _ := s.System.Constants()
+	return perturbation.NewEvolution(basis, levels, s.System.States(), field, d.Perturbation(), hbar)
+}
+
+// Perturbation returns the time dependence of the drive.
+func (d Drive) Perturbation() perturbation.Drive {
+	return perturbation.Drive{Kind: d.Kind, Omega: d.Omega, Start: d.Start, Duration: d.Duration}
+}
+
+// build constructs the undriven system in the superposition of states.
+func (s *Scenario) build(states []systems.State) (systems.System, error) {
+	hbar, mass := s.System.Constants()
+	switch s.System.Kind {
+	case "infinite-well":
+		var width [3]float64
+		copy(width[:], s.System.Width)
+		v0 := 0.0
+		if s.System.Potential.Kind == "constant" {
+			v0 = s.System.Potential.V0
+		}
+		return systems.NewInfiniteWell(width, states, v0, hbar, mass), nil
+	case "finite-well":
+		var width [3]float64
+		copy(width[:], s.System.Width)
+		return systems.NewFiniteWell(width, s.System.Depth, states, hbar, mass)
+	case "double-well":
+		var width [3]float64
+		copy(width[:], s.System.Width)
+		return systems.NewDoubleWell(width, s.System.Barrier.Height, s.System.Barrier.Thickness, states, hbar, mass)
+	case "oscillator":
+		var omega [3]float64
+		copy(omega[:], s.System.Omega)
+		if s.System.Gaussian() {
+			var alpha [3]complex128
+			var squeeze [3]float64
+			for i, a := range s.System.Coherent {
+				alpha[i] = complex(a.Re, a.Im)
+			}
+			copy(squeeze[:], s.System.Squeeze)
+			return systems.NewGaussian(omega, alpha, squeeze, hbar, mass), nil
+		}
+		return systems.NewOscillator(omega, states, hbar, mass), nil
+	case "hydrogen":
+		return systems.NewHydrogen(s.System.Charge, states, s.System.Orbitals == "real", hbar, mass, s.System.Coulomb())
+	case "scattering":
+		if s.System.Incident.Kind == "plane" {
+			return scattering.NewPlaneWave(s.System.Barrier.Profile(), s.System.Incident.Energy, hbar, mass)
+		}
+		return scattering.NewSimulation(s.System.Barrier.Profile(), s.System.Incident.Packet(), s.Grid(), hbar, mass)
+	}
+	return nil, fmt.Errorf("system: unknown kind %q", s.System.Kind)
+}
+
+// Ground returns the lowest quantum number of the system's kind: 0 for an
+// oscillator, 1 for the wells.
+func (s *System) Ground() int {
+	if s.Kind == "oscillator" {
+		return 0
+	}
+	return 1
+}
+
+// Gaussian reports whether the system is an oscillator's coherent or
+// squeezed state rather than a superposition of eigenstates.
+func (s *System) Gaussian() bool {
+	return len(s.Coherent) != 0 || len(s.Squeeze) != 0
+}
+
+// Profile returns the potential the barrier describes.
+func (b Barrier) Profile() scattering.Profile {
+	switch b.Shape {
+	case "step":
+		return scattering.Step(b.At, b.Height)
+	case "double":
+		return scattering.DoubleBarrier(b.At, b.Thickness, b.Gap, b.Height)
+	}
+	return scattering.Barrier(b.At, b.Thickness, b.Height)
+}
+
+// Packet returns the wavepacket the incident wave describes.
+func (in Incident) Packet() scattering.Packet {
+	p := scattering.Packet{Energy: in.Energy, Spread: in.Spread, Angle: in.Angle * math.Pi / 180}
+	copy(p.Start[:], in.Start)
+	return p
+}
+
+// Grid returns the region a scattering packet is evolved over, the width
+// of the system centred on the origin, with the solver's cells or enough
+// for the packet and the barrier.
+func (s *Scenario) Grid() scattering.Grid {
+	var g scattering.Grid
+	for d, w := range s.System.Width {
+		g.Min[d], g.Max[d] = -w/2, w/2
+		if d < len(s.Solver.Grid) {
+			g.Cells[d] = s.Solver.Grid[d]
+		}
+	}
+	hbar, mass := s.System.Constants()
+	return g.Fit(s.System.Barrier.Profile(), s.System.Incident.Packet(), hbar, mass, len(s.System.Width))
+}
+
+// States returns the superposition the system starts in.
+func (s *System) States() []systems.State {
+	if len(s.Initial) == 0 {
+		return []systems.State{{N: quantum(s.Quantum), Amplitude: 1}}
+	}
+	states := make([]systems.State, len(s.Initial))
+	for i, c := range s.Initial {
+		states[i] = systems.State{N: quantum(c.Quantum), Amplitude: complex(c.Re, c.Im)}
+	}
+	return states
+}
+
+func quantum(n []int) [3]int {
+	var q [3]int
+	copy(q[:], n)
+	return q
+}
+
+// Extent returns the sampled box, padded to three dimensions.
+func (s *Scenario) Extent() (min, max [3]float64) {
+	copy(min[:], s.Sampling.Min)
+	copy(max[:], s.Sampling.Extent)
+	return min, max
+}
+
+// AxisLayout returns the x, y and z axes to draw: the sampled box unless the
+// scenario overrides a range, labelled in the scenario's units.
+func (s *Scenario) AxisLayout() [3]axes.Axis {
+	ranges := axes.FromBounds(s.Extent())
+	unit := ""
+	if s.System.Units == "si" {
+		unit = "m"
+	}
+	specs := [3]AxisSpec{s.Axes.X, s.Axes.Y, s.Axes.Z}
+	labels := [3]string{"x", "y", "z"}
+
+	var layout [3]axes.Axis
+	for i, spec := range specs {
+		layout[i] = axes.Axis{Range: ranges[i], Label: labels[i], Unit: unit}
+		if len(spec.Range) == 2 {
+			layout[i].Range = axes.Range{Min: spec.Range[0], Max: spec.Range[1]}
+		}
+		if spec.Label != "" {
+			layout[i].Label = spec.Label
+		}
+		if spec.Unit != "" {
+			layout[i].Unit = spec.Unit
+		}
+	}
+	return layout
+}
+
+// Sample returns the points the scenario's sampling strategy selects for sys
+// at time t.
+func (s *Scenario) Sample(sys systems.Evaluator, t float64) [][]float64 {
+	min, max := s.Extent()
+	switch s.Sampling.Strategy {
+	case "grid":
+		return sampling.Grid(min, max, s.Sampling.Step)
+	case "density":
+		return sampling.Density(sys, t, s.Sampling.Points, min, max, s.Sampling.Seed)
+	}
+	return sampling.Uniform(s.Sampling.Points, min, max, s.Sampling.Seed)
+}
+
+// SampleSet weights points returned by Sample, drawn at time t0, so that
+// observables can be integrated over exactly the points being rendered.
+func (s *Scenario) SampleSet(sys systems.Evaluator, points [][]float64, t0 float64) observables.Set {
+	min, max := s.Extent()
+	switch s.Sampling.Strategy {
+	case "grid":
+		return observables.Grid(points, s.Sampling.Step, min, max)
+	case "density":
+		return observables.Importance(points, func(p []float64) float64 {
+			return systems.Quantity(sys.Evaluate(p[0], p[1], p[2], t0), "probability")
+		})
+	}
+	return observables.Uniform(points, min, max)
+}
+
+// Hamiltonian returns the Hamiltonian in the scenario's units, with a finite
+// difference step small compared to the sampled box.
+func (s *Scenario) Hamiltonian() observables.Hamiltonian {
+	hbar, mass := s.System.Constants()
+	min, max := s.Extent()
+	size := 0.0
+	for d := 0; d < 3; d++ {
+		size = math.Max(size, max[d]-min[d])
+	}
+	return observables.Hamiltonian{Hbar: hbar, Mass: mass, Step: 1e-4 * size}
+}
+
+// ColorMap returns the scenario's colormap.
+func (s *Scenario) ColorMap() colormap.Map {
+	m, err := colormap.Lookup(s.Colormap)
+	if err != nil {
+		return colormap.RedBlue
+	}
+	return m
+}
@@ -0,0 +1,95 @@
+package scenario
+
+import "fmt"
+
+// Parameters lists the values that can be set by name while a viewer runs,
+// as the voltmeter inputs do.
+var Parameters = []string{"v0", "width", "time-scale", "drive", "drive-omega"}
+
+// IsParameter reports whether name is one of Parameters.
+func IsParameter(name string) bool {
+	for _, p := range Parameters {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SetParameter sets one of Parameters.
+func (s *Scenario) SetParameter(name string, v float64) error {
+	switch name {
+	case "v0":
+		s.SetV0(v)
+	case "width":
+		if v <= 0 {
+			return fmt.Errorf("well width must be positive, got %v", v)
+		}
+		s.SetWidth(v)
+	case "time-scale":
+		s.SetTimeScale(v)
+	case "drive":
+		s.System.Drive.Strength = v
+	case "drive-omega":
+		if v < 0 {
+			return fmt.Errorf("drive frequency must not be negative, got %v", v)
+		}
+		s.System.Drive.Omega = v
+	default:
+		return fmt.Errorf("unknown parameter %q", name)
+	}
+	return nil
+}
+
+// V0 returns the value the v0 parameter controls: the depth of a finite
+// well, the height of a scattering or double well barrier, otherwise the
+// strength of the perturbation inside the well.
+func (s *Scenario) V0() float64 {
+	switch s.System.Kind {
+	case "finite-well":
+		return s.System.Depth
+	case "scattering", "double-well":
+		return s.System.Barrier.Height
+	}
+	return s.System.Potential.V0
+}
+
+// SetV0 sets the depth of a finite well or the height of a scattering or
+// double well barrier, or else the strength of a constant perturbation
+// inside the well.
+func (s *Scenario) SetV0(v float64) {
+	switch s.System.Kind {
+	case "finite-well":
+		s.System.Depth = v
+		return
+	case "scattering", "double-well":
+		s.System.Barrier.Height = v
+		return
+	}
+	s.System.Potential.Kind = "constant"
+	s.System.Potential.V0 = v
+}
+
+// SetWidth scales every axis of the well, and the sampled box with it, so
+// the first axis has width w and the well keeps its shape.
+func (s *Scenario) SetWidth(w float64) {
+	ratio := w / s.System.Width[0]
+	for i := range s.System.Width {
+		s.System.Width[i] *= ratio
+		s.Sampling.Min[i] *= ratio
+		s.Sampling.Extent[i] *= ratio
+	}
+}
+
+// SetTimeScale sets the simulated time per second of wall clock. The time
+// step of stepping viewers keeps its ratio to the time scale, including
+// across a pass through zero.
+func (s *Scenario) SetTimeScale(v float64) {
+	if s.stepPerScale == 0 && s.Solver.TimeScale != 0 {
+		s.stepPerScale = s.Solver.TimeStep / s.Solver.TimeScale
+	}
+	s.Solver.TimeScale = v
+	if s.stepPerScale != 0 {
+		s.Solver.TimeStep = v * s.stepPerScale
+	}
+}
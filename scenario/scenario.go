@@ -0,0 +1,682 @@
+// Package scenario loads YAML descriptions of a full simulation run: the
+// system, how it is solved and sampled, how it is coloured, where the camera
+// sits and which files to write. A scenario file checked into version control
+// reproduces an experiment exactly, instead of editing constants in the
+// viewers.
+package scenario
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"gopkg.in/yaml.v2"
+
+	"hackathon/colormap"
+	"hackathon/systems"
+)
+
+// Scenario describes one simulation run.
+type Scenario struct {
+	Name     string   `yaml:"name"`
+	System   System   `yaml:"system"`
+	Solver   Solver   `yaml:"solver"`
+	Sampling Sampling `yaml:"sampling"`
+	Colormap string   `yaml:"colormap"`
+	Display  string   `yaml:"display"` // quantity the viewers colour by, see systems.Quantity
+	Camera   Camera   `yaml:"camera"`
+	Axes     Axes     `yaml:"axes"`
+	Outputs  []Output `yaml:"outputs"`
+	Inputs   []Input  `yaml:"inputs"`
+
+	stepPerScale float64 // time step per unit of time scale, see SetTimeScale
+}
+
+// System describes the potential and the state placed in it.
+type System struct {
+	Kind      string      `yaml:"kind"`      // "infinite-well", "finite-well", "double-well", "oscillator", "hydrogen" or "scattering"
+	Width     []float64   `yaml:"width"`     // well width per dimension, or the size of the region around an oscillator, atom or barrier; its length sets the dimensionality
+	Depth     float64     `yaml:"depth"`     // height of the walls of a finite well
+	Omega     []float64   `yaml:"omega"`     // oscillator angular frequency per dimension
+	Coherent  []Amplitude `yaml:"coherent"`  // coherent amplitude α per dimension, for an oscillator Gaussian state used instead of quantum
+	Squeeze   []float64   `yaml:"squeeze"`   // squeeze parameter r per dimension of the Gaussian state
+	Charge    float64     `yaml:"charge"`    // nuclear charge Z of a hydrogen-like atom
+	Orbitals  string      `yaml:"orbitals"`  // "complex" or "real" orbitals of a hydrogen-like atom
+	Barrier   Barrier     `yaml:"barrier"`   // potential along x that a scattering system's particle meets, or that splits a double well
+	Incident  Incident    `yaml:"incident"`  // wave or packet a scattering system sends at its barrier
+	Potential Potential   `yaml:"potential"` // potential inside the well
+	Drive     Drive       `yaml:"drive"`     // time-dependent perturbation driving transitions between levels
+	Quantum   []int       `yaml:"quantum"`   // quantum numbers of a single eigenstate
+	Initial   []Component `yaml:"initial"`   // superposition, used instead of quantum when given
+	Units     string      `yaml:"units"`     // "natural" (ħ = m = 1) or "si"
+	Hbar      float64     `yaml:"hbar"`      // overrides the unit system's ħ
+	Mass      float64     `yaml:"mass"`      // overrides the unit system's particle mass
+}
+
+// Potential describes the potential energy inside the well.
+type Potential struct {
+	Kind string  `yaml:"kind"` // "none" or "constant"
+	V0   float64 `yaml:"v0"`   // strength of the perturbation
+}
+
+// Drive describes a time-dependent perturbation W(r) f(t), a uniform field
+// along one axis, W = strength × (r - centre), switched on as a step,
+// oscillating as a harmonic drive or shaped as a Gaussian pulse. It drives
+// transitions between the levels of a well or oscillator.
+type Drive struct {
+	Kind     string  `yaml:"kind"`     // "none", "step", "harmonic" or "pulse"
+	Strength float64 `yaml:"strength"` // slope of W along the axis, the force the field exerts
+	Axis     int     `yaml:"axis"`     // 0, 1 or 2 for x, y or z
+	Omega    float64 `yaml:"omega"`    // angular frequency of a harmonic drive or of a pulse's carrier
+	Start    float64 `yaml:"start"`    // when a step or harmonic drive switches on, or the peak of a pulse
+	Duration float64 `yaml:"duration"` // standard deviation of a pulse's Gaussian envelope
+	Levels   [][]int `yaml:"levels"`   // quantum numbers of the levels coupled, the four lowest along the axis when empty
+}
+
+// Component is one eigenstate of an initial superposition.
+type Component struct {
+	Quantum []int   `yaml:"quantum"`
+	Re      float64 `yaml:"re"`
+	Im      float64 `yaml:"im"`
+}
+
+// Amplitude is a complex number, such as a coherent amplitude.
+type Amplitude struct {
+	Re float64 `yaml:"re"`
+	Im float64 `yaml:"im"`
+}
+
+// Barrier describes the potential along x of a scattering system. In 2D it
+// runs the full height of the region along y. A double well uses only the
+// height and thickness of the barrier across its middle.
+type Barrier struct {
+	Shape     string  `yaml:"shape"`     // "step", "barrier" or "double"
+	Height    float64 `yaml:"height"`    // potential of the step or of each barrier
+	Thickness float64 `yaml:"thickness"` // of each barrier
+	Gap       float64 `yaml:"gap"`       // between the two barriers of a double barrier
+	At        float64 `yaml:"at"`        // x of the step or the left face of the first barrier
+}
+
+// Incident describes what a scattering system sends at its barrier from
+// the left.
+type Incident struct {
+	Kind   string    `yaml:"kind"`   // "packet" or "plane"
+	Energy float64   `yaml:"energy"` // mean kinetic energy
+	Spread float64   `yaml:"spread"` // standard deviation of a packet's |ψ|² in position
+	Start  []float64 `yaml:"start"`  // centre of a packet at t = 0
+	Angle  float64   `yaml:"angle"`  // direction of a 2D packet from the x axis, in degrees
+}
+
+// Solver controls how time advances.
+type Solver struct {
+	Method    string  `yaml:"method"`     // "analytic", or "split-step" for scattering packets
+	TimeScale float64 `yaml:"time_scale"` // simulated time per second of wall clock
+	TimeStep  float64 `yaml:"time_step"`  // simulated time per step for stepping viewers
+	Grid      []int   `yaml:"grid"`       // cells per dimension for split-step, chosen from the packet when empty
+}
+
+// Sampling controls which points are evaluated.
+type Sampling struct {
+	Strategy string    `yaml:"strategy"` // "random", "grid" or "density"
+	Points   int       `yaml:"points"`   // number of points for random and density sampling
+	Step     float64   `yaml:"step"`     // lattice spacing for grid sampling
+	Seed     int64     `yaml:"seed"`
+	Min      []float64 `yaml:"min"`    // lower corner of the sampled box, defaults to the origin
+	Extent   []float64 `yaml:"extent"` // upper corner of the sampled box, defaults to the well width
+}
+
+// Camera places the viewer's perspective camera.
+type Camera struct {
+	Position []float64 `yaml:"position"`
+	Target   []float64 `yaml:"target"`
+}
+
+// Axes overrides the axis extents and labels the viewers derive from the
+// sampled box. Axes are in physical order: z is the vertical scene axis.
+type Axes struct {
+	X     AxisSpec `yaml:"x"`
+	Y     AxisSpec `yaml:"y"`
+	Z     AxisSpec `yaml:"z"`
+	Ticks int      `yaml:"ticks"` // approximate number of ticks per axis
+}
+
+// AxisSpec configures one axis.
+type AxisSpec struct {
+	Range []float64 `yaml:"range"` // [min, max], derived from the sampled box when empty
+	Label string    `yaml:"label"`
+	Unit  string    `yaml:"unit"`
+}
+
+// Output is a file written by the headless exporter.
+type Output struct {
+	Kind     string  `yaml:"kind"`     // "csv", "png", or "tunnelling" for a double well's left well probability against time
+	Path     string  `yaml:"path"`     // file to write
+	Time     float64 `yaml:"time"`     // simulated time to evaluate at, or for tunnelling to plot up to, three periods when zero
+	Quantity string  `yaml:"quantity"` // "probability", "real", "imag" or "phase"
+	Plane    string  `yaml:"plane"`    // png only: "xy", "xz" or "yz"
+	Slice    float64 `yaml:"slice"`    // png only: coordinate of the plane on the remaining axis
+	Width    int     `yaml:"width"`    // png and tunnelling plots: image size in pixels
+	Height   int     `yaml:"height"`
+}
+
+// Input binds a voltmeter channel to a scenario parameter, so a knob on the
+// breadboard can steer a live viewer.
+type Input struct {
+	Channel   int       `yaml:"channel"`   // ADC pin
+	Parameter string    `yaml:"parameter"` // one of Parameters
+	Volts     []float64 `yaml:"volts"`     // [low, high] input voltage, defaults to [0, 3.3]
+	Range     []float64 `yaml:"range"`     // parameter values at the low and high voltage
+	Smoothing float64   `yaml:"smoothing"` // time constant in seconds, 0 follows every reading
+}
+
+// Load reads and validates the scenario file at path.
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return s, nil
+}
+
+// Parse decodes a scenario from YAML, fills in defaults and validates it.
+// Unknown keys are rejected so typos do not silently fall back to defaults.
+func Parse(data []byte) (*Scenario, error) {
+	var s Scenario
+	if err := yaml.UnmarshalStrict(data, &s); err != nil {
+		return nil, err
+	}
+	s.SetDefaults()
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Marshal encodes the scenario as YAML.
+func (s *Scenario) Marshal() ([]byte, error) {
+	return yaml.Marshal(s)
+}
+
+// SetDefaults fills in every field left empty.
+func (s *Scenario) SetDefaults() {
+	if s.System.Kind == "" {
+		s.System.Kind = "infinite-well"
+	}
+	if len(s.System.Width) == 0 {
+		s.System.Width = []float64{15, 15, 15}
+	}
+	if s.System.Potential.Kind == "" {
+		s.System.Potential.Kind = "none"
+	}
+	if s.System.Kind == "hydrogen" {
+		if s.System.Charge == 0 {
+			s.System.Charge = 1
+		}
+		if s.System.Orbitals == "" {
+			s.System.Orbitals = "complex"
+		}
+		if len(s.System.Quantum) == 0 && len(s.System.Initial) == 0 {
+			s.System.Quantum = []int{1, 0, 0}
+		}
+	}
+	if s.System.Kind == "double-well" {
+		if s.System.Barrier.Thickness == 0 {
+			s.System.Barrier.Thickness = s.System.Width[0] / 10
+		}
+		if len(s.System.Quantum) == 0 && len(s.System.Initial) == 0 {
+			// The sum of the lowest symmetric and antisymmetric levels starts in the left well
+			for n := 1; n <= 2; n++ {
+				c := Component{Quantum: make([]int, len(s.System.Width)), Re: 1}
+				for i := range c.Quantum {
+					c.Quantum[i] = 1
+				}
+				c.Quantum[0] = n
+				s.System.Initial = append(s.System.Initial, c)
+			}
+		}
+	}
+	if s.System.Kind == "scattering" {
+		if s.System.Barrier.Shape == "" {
+			s.System.Barrier.Shape = "barrier"
+		}
+		if s.System.Incident.Kind == "" {
+			s.System.Incident.Kind = "packet"
+		}
+		if s.System.Incident.Spread == 0 {
+			s.System.Incident.Spread = s.System.Width[0] / 40
+		}
+		if len(s.System.Incident.Start) == 0 {
+			// Start in the left half, clear of the barrier and the absorbing edge
+			s.System.Incident.Start = make([]float64, len(s.System.Width))
+			s.System.Incident.Start[0] = -s.System.Width[0] / 4
+		}
+		if s.Solver.Method == "" && s.System.Incident.Kind == "packet" {
+			s.Solver.Method = "split-step"
+		}
+	}
+	if len(s.System.Quantum) == 0 && len(s.System.Initial) == 0 {
+		s.System.Quantum = make([]int, len(s.System.Width))
+		for i := range s.System.Quantum {
+			s.System.Quantum[i] = s.System.Ground()
+		}
+	}
+	if s.System.Drive.Kind == "" {
+		s.System.Drive.Kind = "none"
+	}
+	if s.System.Drive.Kind != "none" && len(s.System.Drive.Levels) == 0 && s.System.Drive.Axis < len(s.System.Width) {
+		// The ground level along the axis and the three above it, with the
+		// starting state's quantum numbers along the other axes
+		n := s.System.Quantum
+		if len(s.System.Initial) != 0 {
+			n = s.System.Initial[0].Quantum
+		}
+		for q := s.System.Ground(); q < s.System.Ground()+4; q++ {
+			level := append([]int(nil), n...)
+			if s.System.Drive.Axis < len(level) {
+				level[s.System.Drive.Axis] = q
+			}
+			s.System.Drive.Levels = append(s.System.Drive.Levels, level)
+		}
+	}
+	if s.System.Kind == "oscillator" {
+		if len(s.System.Omega) == 0 {
+			s.System.Omega = make([]float64, len(s.System.Width))
+			for i := range s.System.Omega {
+				s.System.Omega[i] = 1
+			}
+		}
+		if s.System.Gaussian() && len(s.System.Coherent) == 0 {
+			s.System.Coherent = make([]Amplitude, len(s.System.Width))
+		}
+		if s.System.Gaussian() && len(s.System.Squeeze) == 0 {
+			s.System.Squeeze = make([]float64, len(s.System.Width))
+		}
+	}
+	if s.System.Units == "" {
+		s.System.Units = "natural"
+	}
+	if s.Solver.Method == "" {
+		s.Solver.Method = "analytic"
+	}
+	if s.Solver.TimeScale == 0 {
+		s.Solver.TimeScale = 1
+	}
+	if s.Solver.TimeStep == 0 {
+		s.Solver.TimeStep = 0.1
+	}
+	if s.Sampling.Strategy == "" {
+		s.Sampling.Strategy = "random"
+	}
+	if s.Sampling.Points == 0 {
+		s.Sampling.Points = 20000
+	}
+	if s.Sampling.Step == 0 {
+		s.Sampling.Step = 1
+	}
+	if s.Sampling.Seed == 0 {
+		s.Sampling.Seed = 38
+	}
+	if len(s.Sampling.Extent) == 0 && len(s.Sampling.Min) == 0 && s.System.Kind == "finite-well" {
+		// Leave a quarter of the width either side so the tails in the walls are drawn
+		for _, w := range s.System.Width {
+			s.Sampling.Min = append(s.Sampling.Min, -w/4)
+			s.Sampling.Extent = append(s.Sampling.Extent, w+w/4)
+		}
+	}
+	if len(s.Sampling.Extent) == 0 && len(s.Sampling.Min) == 0 && (s.System.Kind == "oscillator" || s.System.Kind == "hydrogen" || s.System.Kind == "scattering") {
+		// An oscillator, atom or barrier sits at the origin, so the region drawn is centred on it
+		for _, w := range s.System.Width {
+			s.Sampling.Min = append(s.Sampling.Min, -w/2)
+			s.Sampling.Extent = append(s.Sampling.Extent, w/2)
+		}
+	}
+	if len(s.Sampling.Extent) == 0 {
+		s.Sampling.Extent = append([]float64(nil), s.System.Width...)
+	}
+	if len(s.Sampling.Min) == 0 {
+		s.Sampling.Min = make([]float64, len(s.Sampling.Extent))
+	}
+	if s.Colormap == "" {
+		s.Colormap = "red-blue"
+	}
+	if s.Display == "" {
+		s.Display = "probability"
+	}
+	if len(s.Camera.Position) == 0 {
+		s.Camera.Position = []float64{0, 0, 3}
+	}
+	if len(s.Camera.Target) == 0 {
+		s.Camera.Target = []float64{0, 0, 0}
+	}
+	if s.Axes.Ticks == 0 {
+		s.Axes.Ticks = 5
+	}
+	for i := range s.Outputs {
+		o := &s.Outputs[i]
+		if o.Quantity == "" {
+			o.Quantity = "probability"
+		}
+		if o.Plane == "" {
+			o.Plane = "xy"
+		}
+		if o.Width == 0 {
+			o.Width = 512
+		}
+		if o.Height == 0 {
+			o.Height = 512
+		}
+	}
+	for i := range s.Inputs {
+		if len(s.Inputs[i].Volts) == 0 {
+			s.Inputs[i].Volts = []float64{0, 3.3}
+		}
+	}
+}
+
+// Validate reports the first inconsistency in the scenario.
+func (s *Scenario) Validate() error {
+	dims := len(s.System.Width)
+	if dims < 1 || dims > 3 {
+		return fmt.Errorf("system: width must have 1 to 3 entries, got %d", dims)
+	}
+	for _, w := range s.System.Width {
+		if w <= 0 {
+			return fmt.Errorf("system: well width must be positive, got %v", w)
+		}
+	}
+	switch s.System.Kind {
+	case "infinite-well":
+	case "finite-well":
+		if s.System.Depth <= 0 {
+			return fmt.Errorf("system: finite well depth must be positive, got %v", s.System.Depth)
+		}
+	case "double-well":
+		if s.System.Barrier.Height <= 0 {
+			return fmt.Errorf("system: double well barrier height must be positive, got %v", s.System.Barrier.Height)
+		}
+		if t := s.System.Barrier.Thickness; t <= 0 || t >= s.System.Width[0] {
+			return fmt.Errorf("system: double well barrier thickness must be between 0 and the width, got %v", t)
+		}
+	case "oscillator":
+		if len(s.System.Omega) != dims {
+			return fmt.Errorf("system: omega needs %d entries, one per dimension", dims)
+		}
+		for _, w := range s.System.Omega {
+			if w <= 0 {
+				return fmt.Errorf("system: oscillator frequency must be positive, got %v", w)
+			}
+		}
+		if s.System.Gaussian() && (len(s.System.Coherent) != dims || len(s.System.Squeeze) != dims) {
+			return fmt.Errorf("system: coherent and squeeze need %d entries, one per dimension", dims)
+		}
+	case "hydrogen":
+		if dims != 3 {
+			return fmt.Errorf("system: a hydrogen-like atom is 3D, got %d dimensions", dims)
+		}
+		if s.System.Charge <= 0 {
+			return fmt.Errorf("system: nuclear charge must be positive, got %v", s.System.Charge)
+		}
+		switch s.System.Orbitals {
+		case "complex", "real":
+		default:
+			return fmt.Errorf("system: unknown orbitals %q, want complex or real", s.System.Orbitals)
+		}
+	case "scattering":
+		if dims > 2 {
+			return fmt.Errorf("system: scattering is 1D or 2D, got %d dimensions", dims)
+		}
+		if err := s.System.Barrier.validate(); err != nil {
+			return err
+		}
+		if err := s.System.Incident.validate(dims); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("system: unknown kind %q", s.System.Kind)
+	}
+	if s.System.Gaussian() && s.System.Kind != "oscillator" {
+		return fmt.Errorf("system: coherent and squeezed states need an oscillator, not %q", s.System.Kind)
+	}
+	check := func(n []int) error {
+		return checkQuantum(n, dims, s.System.Ground())
+	}
+	if s.System.Kind == "hydrogen" {
+		check = checkOrbital
+	}
+	if len(s.System.Initial) == 0 {
+		if err := check(s.System.Quantum); err != nil {
+			return err
+		}
+	}
+	norm := 0.0
+	for _, c := range s.System.Initial {
+		if err := check(c.Quantum); err != nil {
+			return err
+		}
+		norm += c.Re*c.Re + c.Im*c.Im
+	}
+	if len(s.System.Initial) > 0 && norm == 0 {
+		return fmt.Errorf("system: initial superposition has no amplitude to normalise")
+	}
+	switch s.System.Potential.Kind {
+	case "none", "constant":
+	default:
+		return fmt.Errorf("system: unknown potential %q", s.System.Potential.Kind)
+	}
+	if err := s.System.Drive.validate(s.System.Kind, dims, s.System.Gaussian()); err != nil {
+		return err
+	}
+	for _, n := range s.System.Drive.Levels {
+		if err := check(n); err != nil {
+			return err
+		}
+	}
+	switch s.System.Units {
+	case "natural", "si":
+	default:
+		return fmt.Errorf("system: unknown units %q", s.System.Units)
+	}
+	switch {
+	case s.Solver.Method == "analytic" && !(s.System.Kind == "scattering" && s.System.Incident.Kind == "packet"):
+	case s.Solver.Method == "split-step" && s.System.Kind == "scattering" && s.System.Incident.Kind == "packet":
+		if len(s.Solver.Grid) != 0 && len(s.Solver.Grid) != dims {
+			return fmt.Errorf("solver: grid needs %d entries, one per dimension", dims)
+		}
+	case s.Solver.Method == "analytic" || s.Solver.Method == "split-step":
+		return fmt.Errorf("solver: method %q does not apply to this system", s.Solver.Method)
+	default:
+		return fmt.Errorf("solver: unknown method %q", s.Solver.Method)
+	}
+	switch s.Sampling.Strategy {
+	case "random", "grid", "density":
+	default:
+		return fmt.Errorf("sampling: unknown strategy %q", s.Sampling.Strategy)
+	}
+	if s.Sampling.Points < 0 || s.Sampling.Step < 0 {
+		return fmt.Errorf("sampling: points and step must be positive")
+	}
+	if len(s.Sampling.Extent) != dims || len(s.Sampling.Min) != dims {
+		return fmt.Errorf("sampling: min and extent need %d entries, one per dimension", dims)
+	}
+	for i := range s.Sampling.Extent {
+		if s.Sampling.Min[i] >= s.Sampling.Extent[i] {
+			return fmt.Errorf("sampling: min %v is not below extent %v", s.Sampling.Min[i], s.Sampling.Extent[i])
+		}
+	}
+	if _, err := colormap.Lookup(s.Colormap); err != nil {
+		return err
+	}
+	if !systems.IsQuantity(s.Display) {
+		return fmt.Errorf("display: unknown quantity %q", s.Display)
+	}
+	if len(s.Camera.Position) != 3 || len(s.Camera.Target) != 3 {
+		return fmt.Errorf("camera: position and target need 3 coordinates")
+	}
+	for name, a := range map[string]AxisSpec{"x": s.Axes.X, "y": s.Axes.Y, "z": s.Axes.Z} {
+		if len(a.Range) != 0 && (len(a.Range) != 2 || a.Range[0] >= a.Range[1]) {
+			return fmt.Errorf("axes: %s range must be [min, max] with min below max", name)
+		}
+	}
+	for i, o := range s.Outputs {
+		switch o.Kind {
+		case "csv", "png":
+		case "tunnelling":
+			if s.System.Kind != "double-well" {
+				return fmt.Errorf("outputs[%d]: tunnelling needs a double well, not a %s", i, s.System.Kind)
+			}
+			if s.System.Drive.Kind != "none" {
+				return fmt.Errorf("outputs[%d]: tunnelling needs a double well left to itself, not driven", i)
+			}
+		default:
+			return fmt.Errorf("outputs[%d]: unknown kind %q", i, o.Kind)
+		}
+		if o.Path == "" {
+			return fmt.Errorf("outputs[%d]: missing path", i)
+		}
+		if !systems.IsQuantity(o.Quantity) {
+			return fmt.Errorf("outputs[%d]: unknown quantity %q", i, o.Quantity)
+		}
+		switch o.Plane {
+		case "xy", "xz", "yz":
+		default:
+			return fmt.Errorf("outputs[%d]: unknown plane %q", i, o.Plane)
+		}
+	}
+	for i, in := range s.Inputs {
+		if !IsParameter(in.Parameter) {
+			return fmt.Errorf("inputs[%d]: unknown parameter %q, want one of %v", i, in.Parameter, Parameters)
+		}
+		if in.Channel < 0 || in.Channel > 15 {
+			return fmt.Errorf("inputs[%d]: channel must be an ADC pin from 0 to 15, got %d", i, in.Channel)
+		}
+		if len(in.Volts) != 2 || in.Volts[0] == in.Volts[1] {
+			return fmt.Errorf("inputs[%d]: volts must be [low, high] with two different voltages", i)
+		}
+		if len(in.Range) != 2 {
+			return fmt.Errorf("inputs[%d]: range must give the parameter at the low and high voltage", i)
+		}
+		if in.Parameter == "width" && (in.Range[0] <= 0 || in.Range[1] <= 0) {
+			return fmt.Errorf("inputs[%d]: a width range must be positive", i)
+		}
+		if in.Smoothing < 0 {
+			return fmt.Errorf("inputs[%d]: smoothing must not be negative", i)
+		}
+	}
+	return nil
+}
+
+func checkQuantum(n []int, dims, ground int) error {
+	if len(n) != dims {
+		return fmt.Errorf("system: %d quantum numbers given for a %dD system", len(n), dims)
+	}
+	for _, q := range n {
+		if q < ground {
+			return fmt.Errorf("system: quantum numbers must be at least %d, got %d", ground, q)
+		}
+	}
+	return nil
+}
+
+func (b Barrier) validate() error {
+	switch b.Shape {
+	case "step":
+	case "barrier", "double":
+		if b.Thickness <= 0 {
+			return fmt.Errorf("system: barrier thickness must be positive, got %v", b.Thickness)
+		}
+		if b.Shape == "double" && b.Gap <= 0 {
+			return fmt.Errorf("system: the gap of a double barrier must be positive, got %v", b.Gap)
+		}
+	default:
+		return fmt.Errorf("system: unknown barrier shape %q, want step, barrier or double", b.Shape)
+	}
+	return nil
+}
+
+func (d Drive) validate(kind string, dims int, gaussian bool) error {
+	switch d.Kind {
+	case "none":
+		return nil
+	case "step":
+	case "harmonic":
+		if d.Omega <= 0 {
+			return fmt.Errorf("system: harmonic drive frequency must be positive, got %v", d.Omega)
+		}
+	case "pulse":
+		if d.Duration <= 0 {
+			return fmt.Errorf("system: pulse duration must be positive, got %v", d.Duration)
+		}
+		if d.Omega < 0 {
+			return fmt.Errorf("system: pulse carrier frequency must not be negative, got %v", d.Omega)
+		}
+	default:
+		return fmt.Errorf("system: unknown drive %q, want none, step, harmonic or pulse", d.Kind)
+	}
+	switch kind {
+	case "infinite-well", "finite-well", "double-well":
+	case "oscillator":
+		if gaussian {
+			return fmt.Errorf("system: a drive needs the oscillator's eigenstates, not a coherent or squeezed state")
+		}
+	default:
+		return fmt.Errorf("system: a drive needs a well or an oscillator, not a %s", kind)
+	}
+	if d.Axis < 0 || d.Axis >= dims {
+		return fmt.Errorf("system: drive axis must be below %d, got %d", dims, d.Axis)
+	}
+	return nil
+}
+
+func (in Incident) validate(dims int) error {
+	switch in.Kind {
+	case "packet", "plane":
+	default:
+		return fmt.Errorf("system: unknown incident %q, want packet or plane", in.Kind)
+	}
+	if in.Energy <= 0 {
+		return fmt.Errorf("system: incident energy must be positive, got %v", in.Energy)
+	}
+	if in.Spread <= 0 {
+		return fmt.Errorf("system: packet spread must be positive, got %v", in.Spread)
+	}
+	if len(in.Start) != dims {
+		return fmt.Errorf("system: packet start needs %d coordinates", dims)
+	}
+	return nil
+}
+
+// checkOrbital checks that n holds the (n, l, m) of a hydrogen-like orbital.
+func checkOrbital(n []int) error {
+	if len(n) != 3 {
+		return fmt.Errorf("system: an orbital needs quantum numbers (n, l, m), got %d numbers", len(n))
+	}
+	if n[0] < 1 || n[1] < 0 || n[1] >= n[0] || n[2] < -n[1] || n[2] > n[1] {
+		return fmt.Errorf("system: no orbital has n = %d, l = %d, m = %d: need n ≥ 1, 0 ≤ l < n and |m| ≤ l", n[0], n[1], n[2])
+	}
+	return nil
+}
+
+// FromFlags registers a -scenario flag, parses the command line and returns
+// the scenario it names, or def when the flag is not given. It exits the
+// program if the file cannot be loaded.
+func FromFlags(def *Scenario) *Scenario {
+	path := flag.String("scenario", "", "YAML scenario file describing the run")
+	flag.Parse()
+	if *path == "" {
+		def.SetDefaults()
+		if err := def.Validate(); err != nil {
+			log.Fatalf("default scenario: %v", err)
+		}
+		return def
+	}
+	s, err := Load(*path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return s
+}
@@ -0,0 +1,111 @@
+package stripchart
+
+import (
+	"image"
+	"image/draw"
+	"math"
+
+	"hackathon/axes"
+)
+
+// Trace is one line on a pane of a Plot.
+type Trace struct {
+	Label string
+	X, Y  []float64
+	Color int  // index into the palette, so a pin's traces keep its colour
+	Marks bool // draw each point as a cross instead of joining them
+}
+
+// Pane is one graph of a Plot, scaled to fit its own traces.
+type Pane struct {
+	Title  string
+	XLabel string // unit or name shown after the x tick labels
+	Traces []Trace
+}
+
+// Plot draws panes stacked top to bottom, for graphs of a whole recording
+// rather than the live window Render follows.
+func Plot(panes []Pane, opts Options) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+	if opts.Ticks == 0 {
+		opts.Ticks = 5
+	}
+	if len(panes) == 0 {
+		label(img, marginLeft, opts.Height/2, "nothing to plot")
+		return img
+	}
+	paneHeight := opts.Height / len(panes)
+
+	for i, p := range panes {
+		area := image.Rect(marginLeft, i*paneHeight+marginTop, opts.Width-marginRight, (i+1)*paneHeight-marginBottom)
+		if area.Dx() <= 0 || area.Dy() <= 0 {
+			continue
+		}
+		xs, ys := extent(p.Traces)
+		toX := func(x float64) int {
+			return area.Min.X + int(math.Round((x-xs.Min)/xs.Length()*float64(area.Dx())))
+		}
+		toY := func(y float64) int {
+			return area.Max.Y - int(math.Round((y-ys.Min)/ys.Length()*float64(area.Dy())))
+		}
+
+		for _, v := range axes.Ticks(ys, opts.Ticks) {
+			y := toY(v)
+			hline(img, area.Min.X, area.Max.X, y, grid)
+			hline(img, area.Min.X-tickLength, area.Min.X, y, frame)
+			label(img, 4, y+4, axes.Format(v))
+		}
+		for _, x := range axes.Ticks(xs, opts.Ticks) {
+			px := toX(x)
+			vline(img, px, area.Min.Y, area.Max.Y, grid)
+			vline(img, px, area.Max.Y, area.Max.Y+tickLength, frame)
+			label(img, px-8, area.Max.Y+tickLength+12, axes.Format(x)+" "+p.XLabel)
+		}
+		outline(img, area, frame)
+
+		// Traces, then the title followed by each trace's label in its colour
+		x := area.Min.X
+		drawText(img, x, area.Min.Y-6, p.Title, textColor)
+		x += 7 * (len([]rune(p.Title)) + 2)
+		for _, tr := range p.Traces {
+			c := palette[tr.Color%len(palette)]
+			for k := range tr.X {
+				if tr.Marks {
+					px, py := toX(tr.X[k]), toY(tr.Y[k])
+					line(img, px-3, py-3, px+3, py+3, c, area)
+					line(img, px-3, py+3, px+3, py-3, c, area)
+				} else if k > 0 {
+					line(img, toX(tr.X[k-1]), toY(tr.Y[k-1]), toX(tr.X[k]), toY(tr.Y[k]), c, area)
+				}
+			}
+			if tr.Label != "" {
+				drawText(img, x, area.Min.Y-6, tr.Label, c)
+				x += 7 * (len([]rune(tr.Label)) + 2)
+			}
+		}
+	}
+	return img
+}
+
+// extent returns the ranges covering every point of traces, with a margin
+// above and below the data.
+func extent(traces []Trace) (x, y axes.Range) {
+	x = axes.Range{Min: math.Inf(1), Max: math.Inf(-1)}
+	y = x
+	for _, tr := range traces {
+		for k := range tr.X {
+			x.Min, x.Max = math.Min(x.Min, tr.X[k]), math.Max(x.Max, tr.X[k])
+			y.Min, y.Max = math.Min(y.Min, tr.Y[k]), math.Max(y.Max, tr.Y[k])
+		}
+	}
+	if math.IsInf(x.Min, 1) {
+		return axes.Range{Min: 0, Max: 1}, axes.Range{Min: 0, Max: 1}
+	}
+	if x.Length() == 0 {
+		x.Min, x.Max = x.Min-0.5, x.Max+0.5
+	}
+	center, half := (y.Max+y.Min)/2, (y.Max-y.Min)/2
+	half = math.Max(half*1.1, 0.005)
+	return x, axes.Range{Min: center - half, Max: center + half}
+}
@@ -0,0 +1,190 @@
+package stripchart
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+
+	"hackathon/axes"
+)
+
+// Layout of each pane in pixels
+const (
+	marginLeft   = 56
+	marginRight  = 12
+	marginTop    = 20
+	marginBottom = 18
+	tickLength   = 4
+)
+
+var (
+	background = color.RGBA{R: 20, G: 20, B: 28, A: 255}
+	frame      = color.RGBA{R: 90, G: 90, B: 110, A: 255}
+	grid       = color.RGBA{R: 40, G: 40, B: 52, A: 255}
+	textColor  = color.RGBA{R: 220, G: 220, B: 220, A: 255}
+
+	// One colour per ADC pin, repeating after six
+	palette = []color.RGBA{
+		{R: 240, G: 80, B: 80, A: 255},
+		{R: 80, G: 160, B: 255, A: 255},
+		{R: 90, G: 210, B: 110, A: 255},
+		{R: 240, G: 190, B: 60, A: 255},
+		{R: 200, G: 110, B: 230, A: 255},
+		{R: 70, G: 210, B: 210, A: 255},
+	}
+)
+
+// Options control how a chart is drawn.
+type Options struct {
+	Width, Height int
+	Ticks         int              // approximate ticks per axis
+	Name          func(int) string // label of an ADC pin, ADC<n> when nil
+}
+
+// Render draws one pane per channel, stacked top to bottom, each scaled to
+// its own readings. Time runs from -Span on the left to the newest reading
+// at 0 on the right.
+func Render(w *Window, opts Options) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+	if opts.Ticks == 0 {
+		opts.Ticks = 5
+	}
+	if opts.Name == nil {
+		opts.Name = func(pin int) string { return "ADC" + strconv.Itoa(pin) }
+	}
+
+	pins := w.Channels()
+	if len(pins) == 0 {
+		label(img, marginLeft, opts.Height/2, "waiting for readings")
+		return img
+	}
+	span := w.Span.Seconds()
+	timeRange := axes.Range{Min: -span, Max: 0}
+	paneHeight := opts.Height / len(pins)
+
+	for i, pin := range pins {
+		pane := image.Rect(marginLeft, i*paneHeight+marginTop, opts.Width-marginRight, (i+1)*paneHeight-marginBottom)
+		if pane.Dx() <= 0 || pane.Dy() <= 0 {
+			continue
+		}
+		volts := w.Scale(pin)
+		toX := func(t float64) int {
+			return pane.Min.X + int(math.Round((t-timeRange.Min)/timeRange.Length()*float64(pane.Dx())))
+		}
+		toY := func(v float64) int {
+			return pane.Max.Y - int(math.Round((v-volts.Min)/volts.Length()*float64(pane.Dy())))
+		}
+
+		// Grid lines and tick labels
+		for _, v := range axes.Ticks(volts, opts.Ticks) {
+			y := toY(v)
+			hline(img, pane.Min.X, pane.Max.X, y, grid)
+			hline(img, pane.Min.X-tickLength, pane.Min.X, y, frame)
+			label(img, 4, y+4, axes.Format(v))
+		}
+		for _, t := range axes.Ticks(timeRange, opts.Ticks) {
+			x := toX(t)
+			vline(img, x, pane.Min.Y, pane.Max.Y, grid)
+			vline(img, x, pane.Max.Y, pane.Max.Y+tickLength, frame)
+			if i == len(pins)-1 {
+				label(img, x-8, pane.Max.Y+tickLength+12, axes.Format(t)+" s")
+			}
+		}
+		outline(img, pane, frame)
+
+		// Trace, relative to the newest reading in the window
+		c := palette[pin%len(palette)]
+		pts := w.Points(pin)
+		for k := 1; k < len(pts); k++ {
+			a, b := pts[k-1], pts[k]
+			line(img, toX(a.T-w.latest), toY(a.V), toX(b.T-w.latest), toY(b.V), c, pane)
+		}
+
+		// Readouts above the pane
+		s := w.Stats(pin)
+		title := fmt.Sprintf("%s  last %.4f V  min %.4f V  max %.4f V  mean %.4f V  (%d)",
+			opts.Name(pin), s.Last, s.Min, s.Max, s.Mean, s.N)
+		drawText(img, pane.Min.X, pane.Min.Y-6, title, c)
+	}
+	return img
+}
+
+// label writes tick and status text.
+func label(img *image.RGBA, x, y int, s string) {
+	drawText(img, x, y, s, textColor)
+}
+
+// drawText writes s with its baseline at y.
+func drawText(img *image.RGBA, x, y int, s string, c color.Color) {
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
+
+func hline(img *image.RGBA, x0, x1, y int, c color.RGBA) {
+	for x := x0; x <= x1; x++ {
+		img.SetRGBA(x, y, c)
+	}
+}
+
+func vline(img *image.RGBA, x, y0, y1 int, c color.RGBA) {
+	for y := y0; y <= y1; y++ {
+		img.SetRGBA(x, y, c)
+	}
+}
+
+func outline(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	hline(img, r.Min.X, r.Max.X, r.Min.Y, c)
+	hline(img, r.Min.X, r.Max.X, r.Max.Y, c)
+	vline(img, r.Min.X, r.Min.Y, r.Max.Y, c)
+	vline(img, r.Max.X, r.Min.Y, r.Max.Y, c)
+}
+
+// line draws a segment with Bresenham's algorithm, clipped to clip.
+func line(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA, clip image.Rectangle) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		if (image.Point{X: x0, Y: y0}).In(clip.Inset(-1)) {
+			img.SetRGBA(x0, y0, c)
+		}
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,111 @@
+// Package stripchart keeps the last few seconds of voltmeter readings per
+// channel and draws them as scrolling line graphs. Rendering is plain Go
+// onto an image, so the same chart is shown in the g3n window and written
+// to PNG snapshots. Plot draws graphs of whole recordings in the same style.
+package stripchart
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"hackathon/axes"
+	"voltmeter/acquire"
+)
+
+// Point is one reading, t seconds after the first reading in the window.
+type Point struct {
+	T, V float64
+}
+
+// Stats summarises a channel over the window.
+type Stats struct {
+	Min, Max, Mean, Last float64
+	N                    int
+}
+
+// Window holds the readings of the last Span of time.
+type Window struct {
+	Span   time.Duration
+	start  time.Time
+	latest float64 // time of the newest reading
+	series map[int][]Point
+}
+
+// NewWindow returns an empty window covering span.
+func NewWindow(span time.Duration) *Window {
+	return &Window{Span: span, series: map[int][]Point{}}
+}
+
+// Add appends a reading and drops everything older than the span.
+func (w *Window) Add(r acquire.Reading) {
+	if w.start.IsZero() {
+		w.start = r.Received
+	}
+	t := r.Received.Sub(w.start).Seconds()
+	if t > w.latest {
+		w.latest = t
+	}
+	for i, pin := range r.Channels {
+		w.series[pin] = append(w.series[pin], Point{T: t, V: r.Volts[i]})
+	}
+
+	// Trim from the front, channels that stopped being sampled empty out
+	cutoff := w.latest - w.Span.Seconds()
+	for pin, pts := range w.series {
+		n := sort.Search(len(pts), func(i int) bool { return pts[i].T >= cutoff })
+		if n == len(pts) {
+			delete(w.series, pin)
+			continue
+		}
+		w.series[pin] = append(pts[:0], pts[n:]...)
+	}
+}
+
+// Channels returns the ADC pins with readings in the window, in order.
+func (w *Window) Channels() []int {
+	pins := make([]int, 0, len(w.series))
+	for pin := range w.series {
+		pins = append(pins, pin)
+	}
+	sort.Ints(pins)
+	return pins
+}
+
+// Points returns the readings of a channel, oldest first.
+func (w *Window) Points(pin int) []Point {
+	return w.series[pin]
+}
+
+// Latest returns the time of the newest reading.
+func (w *Window) Latest() float64 {
+	return w.latest
+}
+
+// Stats returns the minimum, maximum, mean and newest value of a channel.
+func (w *Window) Stats(pin int) Stats {
+	pts := w.series[pin]
+	if len(pts) == 0 {
+		return Stats{}
+	}
+	s := Stats{Min: math.Inf(1), Max: math.Inf(-1), N: len(pts), Last: pts[len(pts)-1].V}
+	for _, p := range pts {
+		s.Min = math.Min(s.Min, p.V)
+		s.Max = math.Max(s.Max, p.V)
+		s.Mean += p.V
+	}
+	s.Mean /= float64(len(pts))
+	return s
+}
+
+// Scale returns a voltage range that fits a channel with a little headroom,
+// widened to at least 10 mV so a flat signal is still drawn mid-pane.
+func (w *Window) Scale(pin int) axes.Range {
+	s := w.Stats(pin)
+	if s.N == 0 {
+		return axes.Range{Min: 0, Max: 1}
+	}
+	center, half := (s.Max+s.Min)/2, (s.Max-s.Min)/2
+	half = math.Max(half*1.1, 0.005)
+	return axes.Range{Min: center - half, Max: center + half}
+}
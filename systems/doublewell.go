@@ -0,0 +1,273 @@
+package systems
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// DoubleWell is a particle in a box with infinitely high walls whose x axis
+// is split in two by a barrier across its middle, so a state started in one
+// half tunnels through to the other and back. Its levels along x have no
+// closed form and are found numerically. They come in pairs, one symmetric
+// and one antisymmetric about the barrier, split in energy by the
+// tunnelling. Along y and z the box is an ordinary infinite well, and as
+// with InfiniteWell an axis of zero width is unused.
+type DoubleWell struct {
+	Width     [3]float64
+	Barrier   float64 // height of the barrier above the floor of the wells
+	Thickness float64 // of the barrier, centred on x = Width[0]/2
+	States    []State // normalised superposition
+	Hbar      float64
+	Mass      float64
+
+	levels []xlevel // levels along x, lowest first
+}
+
+// wellCells is how many cells each half of the x axis is divided into when
+// solving for its levels.
+const wellCells = 200
+
+// xlevel is one level along x: its energy and its values at the centres of
+// the cells of the left half, normalised over the whole well. The right
+// half mirrors them, changing sign for an antisymmetric level.
+type xlevel struct {
+	energy float64
+	even   bool
+	values []float64
+}
+
+// NewDoubleWell solves for as many levels along x as the superposition
+// needs, and at least the lowest pair, and returns a well holding it,
+// normalised. The levels are signed so each is positive where it is
+// largest in the left half: the sum of the lowest pair then sits in the
+// left well and their difference in the right.
+func NewDoubleWell(width [3]float64, barrier, thickness float64, states []State, hbar, mass float64) (*DoubleWell, error) {
+	if width[0] <= 0 {
+		return nil, fmt.Errorf("double well needs a width along x, got %v", width[0])
+	}
+	if barrier <= 0 {
+		return nil, fmt.Errorf("double well barrier height must be positive, got %v", barrier)
+	}
+	if thickness <= 0 || thickness >= width[0] {
+		return nil, fmt.Errorf("double well barrier thickness must be between 0 and the width %v, got %v", width[0], thickness)
+	}
+	count := 2
+	for _, s := range states {
+		if s.N[0] < 1 || s.N[0] > wellCells {
+			return nil, fmt.Errorf("double well levels along x run from 1 to %d, cannot hold n = %d", wellCells, s.N[0])
+		}
+		count = max(count, s.N[0])
+	}
+
+	w := &DoubleWell{Width: width, Barrier: barrier, Thickness: thickness, Hbar: hbar, Mass: mass}
+	for _, even := range []bool{true, false} {
+		levels, err := w.solveHalf(even, count)
+		if err != nil {
+			return nil, err
+		}
+		w.levels = append(w.levels, levels...)
+	}
+	sort.Slice(w.levels, func(i, j int) bool { return w.levels[i].energy < w.levels[j].energy })
+	w.levels = w.levels[:count]
+	w.States = normalise(states)
+	return w, nil
+}
+
+// solveHalf finds the lowest count levels of one parity by finite
+// differences over the left half of the x axis, at the centres of
+// wellCells cells. The wall half a cell before the first centre makes ψ
+// vanish there, and at the centre of the barrier a symmetric level is flat
+// and an antisymmetric one vanishes; each becomes a mirrored neighbour in
+// the second difference of the end cells. Each cell holds the mean of the
+// barrier over it, so the barrier keeps its thickness between grid points.
+func (w *DoubleWell) solveHalf(even bool, count int) ([]xlevel, error) {
+	half := w.Width[0] / 2
+	h := half / wellCells
+	scale := w.Hbar * w.Hbar / (2 * w.Mass * h * h)
+
+	H := mat.NewSymDense(wellCells, nil)
+	for i := 0; i < wellCells; i++ {
+		lo, hi := float64(i)*h, float64(i+1)*h
+		inside := math.Max(0, hi-math.Max(lo, half-w.Thickness/2))
+		H.SetSym(i, i, 2*scale+w.Barrier*inside/h)
+		if i > 0 {
+			H.SetSym(i, i-1, -scale)
+		}
+	}
+	H.SetSym(0, 0, H.At(0, 0)+scale)
+	last := wellCells - 1
+	if even {
+		H.SetSym(last, last, H.At(last, last)-scale)
+	} else {
+		H.SetSym(last, last, H.At(last, last)+scale)
+	}
+
+	var eig mat.EigenSym
+	if !eig.Factorize(H, true) {
+		return nil, fmt.Errorf("double well levels did not converge")
+	}
+	energies := eig.Values(nil)
+	var vectors mat.Dense
+	eig.VectorsTo(&vectors)
+
+	levels := make([]xlevel, min(count, wellCells))
+	for j := range levels {
+		values := mat.Col(nil, j, &vectors)
+		// Half the probability lies in each half, and the largest value is positive
+		sum, peak := 0.0, 0.0
+		for _, v := range values {
+			sum += v * v * h
+			if math.Abs(v) > math.Abs(peak) {
+				peak = v
+			}
+		}
+		norm := math.Copysign(math.Sqrt(0.5/sum), peak)
+		for i := range values {
+			values[i] *= norm
+		}
+		levels[j] = xlevel{energy: energies[j], even: even, values: values}
+	}
+	return levels, nil
+}
+
+// value evaluates a level at x, interpolating between cell centres with a
+// cubic so the Laplacian of the result stays close to that of the level.
+// Beyond the wall and the centre the cells are mirrored as the boundary
+// conditions require.
+func (l xlevel) value(x, width float64) float64 {
+	if x < 0 || x > width {
+		return 0
+	}
+	sign := 1.0
+	if x > width/2 {
+		x = width - x
+		if !l.even {
+			sign = -1
+		}
+	}
+	n := len(l.values)
+	at := func(i int) float64 {
+		switch {
+		case i < 0:
+			return -l.values[-1-i]
+		case i >= n && l.even:
+			return l.values[2*n-1-i]
+		case i >= n:
+			return -l.values[2*n-1-i]
+		}
+		return l.values[i]
+	}
+	h := width / 2 / float64(n)
+	f := x/h - 0.5
+	i := int(math.Floor(f))
+	a := f - float64(i)
+	p0, p1, p2, p3 := at(i-1), at(i), at(i+1), at(i+2)
+	cubic := 2*p1 + (p2-p0)*a + (2*p0-5*p1+4*p2-p3)*a*a + (3*p1-p0-3*p2+p3)*a*a*a
+	return sign * cubic / 2
+}
+
+// Energy returns the energy of the eigenstate with quantum numbers n, the
+// level along x counted from the lowest and the other axes as in an
+// infinite well.
+func (w *DoubleWell) Energy(n [3]int) float64 {
+	e := w.levels[n[0]-1].energy
+	for i := 1; i < 3; i++ {
+		if a := w.Width[i]; a != 0 {
+			k := float64(n[i]) * math.Pi / a
+			e += w.Hbar * w.Hbar * k * k / (2 * w.Mass)
+		}
+	}
+	return e
+}
+
+// Symmetric reports whether level n along x is symmetric about the barrier.
+func (w *DoubleWell) Symmetric(n int) bool {
+	return w.levels[n-1].even
+}
+
+// Eigenstate returns the stationary spatial part of the eigenstate n.
+func (w *DoubleWell) Eigenstate(n [3]int, x, y, z float64) float64 {
+	pos := [3]float64{x, y, z}
+	result := w.levels[n[0]-1].value(x, w.Width[0])
+	for i := 1; i < 3; i++ {
+		a := w.Width[i]
+		if a == 0 {
+			continue
+		}
+		if pos[i] < 0 || pos[i] > a {
+			return 0
+		}
+		result *= math.Sqrt(2/a) * math.Sin(float64(n[i])*math.Pi*pos[i]/a)
+	}
+	return result
+}
+
+// Evaluate returns ψ(x, y, z, t) for the superposition.
+func (w *DoubleWell) Evaluate(x, y, z, t float64) complex128 {
+	var waveFunction complex128
+	for _, s := range w.States {
+		phase := -w.Energy(s.N) * t / w.Hbar
+		waveFunction += s.Amplitude * complex(w.Eigenstate(s.N, x, y, z), 0) * cmplx.Exp(complex(0, phase))
+	}
+	return waveFunction
+}
+
+// Potential returns the barrier height inside the barrier, zero elsewhere
+// in the box and +Inf outside it.
+func (w *DoubleWell) Potential(x, y, z float64) float64 {
+	pos := [3]float64{x, y, z}
+	for i, a := range w.Width {
+		if a != 0 && (pos[i] < 0 || pos[i] > a) {
+			return math.Inf(1)
+		}
+	}
+	if math.Abs(x-w.Width[0]/2) < w.Thickness/2 {
+		return w.Barrier
+	}
+	return 0
+}
+
+// Bounds returns the box [0, Width] along each axis.
+func (w *DoubleWell) Bounds() (min, max [3]float64) {
+	return [3]float64{}, w.Width
+}
+
+// Period returns the tunnelling period 2πħ/ΔE, where ΔE splits the lowest
+// symmetric and antisymmetric levels. A state started in one well is back
+// there after each period.
+func (w *DoubleWell) Period() float64 {
+	return 2 * math.Pi * w.Hbar / (w.levels[1].energy - w.levels[0].energy)
+}
+
+// Left returns the probability of finding the particle in the left half of
+// the box, x < Width[0]/2, at time t. Every level puts half its probability
+// on each side, so only pairs of opposite symmetry, with the same quantum
+// numbers along y and z, move it from one half to the other; they beat at
+// the difference of their energies.
+func (w *DoubleWell) Left(t float64) float64 {
+	p := 0.0
+	for _, a := range w.States {
+		for _, b := range w.States {
+			if a.N[1] != b.N[1] || a.N[2] != b.N[2] {
+				continue
+			}
+			phase := cmplx.Exp(complex(0, (w.Energy(a.N)-w.Energy(b.N))*t/w.Hbar))
+			p += real(cmplx.Conj(a.Amplitude)*b.Amplitude*phase) * w.overlap(a.N[0], b.N[0])
+		}
+	}
+	return p
+}
+
+// overlap returns ∫ φₘφₙ dx over the left half of the box.
+func (w *DoubleWell) overlap(m, n int) float64 {
+	h := w.Width[0] / 2 / wellCells
+	sum := 0.0
+	for i, v := range w.levels[m-1].values {
+		sum += v * w.levels[n-1].values[i] * h
+	}
+	return sum
+}
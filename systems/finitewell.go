@@ -0,0 +1,217 @@
+package systems
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// FiniteWell is a particle in a 1D, 2D or 3D box whose walls have finite
+// height, so its eigenstates tunnel into them with exponential tails. The
+// potential is separable: Depth is added for each axis on which the
+// particle is outside [0, Width], which makes every eigenstate a product of
+// 1D finite well states. As with InfiniteWell an axis of zero width is
+// unused.
+type FiniteWell struct {
+	Width  [3]float64
+	Depth  float64 // height of each wall above the floor of the well
+	States []State // normalised superposition
+	Hbar   float64
+	Mass   float64
+
+	levels [3][]level // bound states of each axis, lowest first
+}
+
+// level is one bound state of a 1D finite well of half width L centred on
+// the origin: A cos(ku) or A sin(ku) inside, decaying as exp(-κ(|u|-L))
+// outside.
+type level struct {
+	k, kappa float64
+	norm     float64 // A
+	even     bool
+	outside  float64 // probability of being found in the walls
+}
+
+// NewFiniteWell solves for the bound states of each axis and returns a well
+// holding the given superposition, normalised. It fails if a state asks
+// for a level the well is too shallow to bind.
+func NewFiniteWell(width [3]float64, depth float64, states []State, hbar, mass float64) (*FiniteWell, error) {
+	if depth <= 0 {
+		return nil, fmt.Errorf("finite well depth must be positive, got %v", depth)
+	}
+	w := &FiniteWell{Width: width, Depth: depth, Hbar: hbar, Mass: mass}
+	for i, a := range width {
+		if a != 0 {
+			w.levels[i] = boundLevels(a/2, depth, hbar, mass)
+		}
+	}
+
+	for _, s := range states {
+		for i, a := range width {
+			if a != 0 && (s.N[i] < 1 || s.N[i] > len(w.levels[i])) {
+				return nil, fmt.Errorf("finite well binds %d states along axis %d, cannot hold n = %d", len(w.levels[i]), i, s.N[i])
+			}
+		}
+	}
+	w.States = normalise(states)
+	return w, nil
+}
+
+// boundLevels solves the transcendental equations of a 1D well of half
+// width L and depth v0. With ξ = kL, η = κL and ξ² + η² = z0², even states
+// satisfy ξ tan ξ = η and odd states -ξ cot ξ = η. Level n has ξ between
+// (n-1)π/2 and nπ/2, where the left hand side climbs from 0 to infinity,
+// so each root is bracketed and found by bisection.
+func boundLevels(L, v0, hbar, mass float64) []level {
+	z0 := L * math.Sqrt(2*mass*v0) / hbar
+	var levels []level
+	for n := 1; float64(n-1)*math.Pi/2 < z0; n++ {
+		even := n%2 == 1
+		f := func(xi float64) float64 {
+			eta := math.Sqrt(math.Max(z0*z0-xi*xi, 0))
+			if even {
+				return xi*math.Sin(xi) - eta*math.Cos(xi)
+			}
+			return -xi*math.Cos(xi) - eta*math.Sin(xi)
+		}
+		lo, hi := float64(n-1)*math.Pi/2, math.Min(float64(n)*math.Pi/2, z0)
+		// f changes sign once in the bracket; make f(lo) negative
+		sign := 1.0
+		if f(lo) > 0 {
+			sign = -1
+		}
+		for i := 0; i < 100; i++ {
+			mid := (lo + hi) / 2
+			if sign*f(mid) < 0 {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		xi := (lo + hi) / 2
+		eta := math.Sqrt(math.Max(z0*z0-xi*xi, 0))
+		if eta == 0 {
+			break // only just unbound, the tail would never decay
+		}
+		l := level{k: xi / L, kappa: eta / L, even: even}
+
+		// ∫|ψ|² over the inside and both tails
+		edge := math.Cos(xi)
+		inside := L + math.Sin(2*xi)/(2*l.k)
+		if !even {
+			edge = math.Sin(xi)
+			inside = L - math.Sin(2*xi)/(2*l.k)
+		}
+		tails := edge * edge / l.kappa
+		l.norm = 1 / math.Sqrt(inside+tails)
+		l.outside = tails / (inside + tails)
+		levels = append(levels, l)
+	}
+	return levels
+}
+
+// value evaluates a level at u, measured from the centre of a well of half
+// width L.
+func (l level) value(u, L float64) float64 {
+	if math.Abs(u) <= L {
+		if l.even {
+			return l.norm * math.Cos(l.k*u)
+		}
+		return l.norm * math.Sin(l.k*u)
+	}
+	tail := math.Exp(-l.kappa * (math.Abs(u) - L))
+	if l.even {
+		return l.norm * math.Cos(l.k*L) * tail
+	}
+	if u < 0 {
+		tail = -tail
+	}
+	return l.norm * math.Sin(l.k*L) * tail
+}
+
+// Bound returns how many states the well binds along an axis.
+func (w *FiniteWell) Bound(axis int) int {
+	return len(w.levels[axis])
+}
+
+// Energy returns the energy of the eigenstate with quantum numbers n,
+// measured from the floor of the well.
+func (w *FiniteWell) Energy(n [3]int) float64 {
+	sum := 0.0
+	for i, a := range w.Width {
+		if a == 0 {
+			continue
+		}
+		k := w.levels[i][n[i]-1].k
+		sum += k * k
+	}
+	return w.Hbar * w.Hbar * sum / (2 * w.Mass)
+}
+
+// Eigenstate returns the stationary spatial part of the eigenstate n.
+func (w *FiniteWell) Eigenstate(n [3]int, x, y, z float64) float64 {
+	pos := [3]float64{x, y, z}
+	result := 1.0
+	for i, a := range w.Width {
+		if a == 0 {
+			continue
+		}
+		result *= w.levels[i][n[i]-1].value(pos[i]-a/2, a/2)
+	}
+	return result
+}
+
+// Evaluate returns ψ(x, y, z, t) for the superposition.
+func (w *FiniteWell) Evaluate(x, y, z, t float64) complex128 {
+	var waveFunction complex128
+	for _, s := range w.States {
+		phase := -w.Energy(s.N) * t / w.Hbar
+		waveFunction += s.Amplitude * complex(w.Eigenstate(s.N, x, y, z), 0) * cmplx.Exp(complex(0, phase))
+	}
+	return waveFunction
+}
+
+// Potential returns Depth for each axis on which (x, y, z) is in a wall.
+func (w *FiniteWell) Potential(x, y, z float64) float64 {
+	pos := [3]float64{x, y, z}
+	v := 0.0
+	for i, a := range w.Width {
+		if a != 0 && (pos[i] < 0 || pos[i] > a) {
+			v += w.Depth
+		}
+	}
+	return v
+}
+
+// Bounds returns the well widened on each side until the slowest decaying
+// tail of the superposition has fallen by e^-10, beyond which less than
+// e^-20 of the probability lies.
+func (w *FiniteWell) Bounds() (min, max [3]float64) {
+	for i, a := range w.Width {
+		if a == 0 {
+			continue
+		}
+		pad := 0.0
+		for _, s := range w.States {
+			pad = math.Max(pad, 10/w.levels[i][s.N[i]-1].kappa)
+		}
+		min[i], max[i] = -pad, a+pad
+	}
+	return min, max
+}
+
+// Leakage returns the probability of finding the particle in the walls,
+// averaged over time so the interference between components drops out.
+func (w *FiniteWell) Leakage() float64 {
+	total := 0.0
+	for _, s := range w.States {
+		inside := 1.0
+		for i, a := range w.Width {
+			if a != 0 {
+				inside *= 1 - w.levels[i][s.N[i]-1].outside
+			}
+		}
+		total += (real(s.Amplitude)*real(s.Amplitude) + imag(s.Amplitude)*imag(s.Amplitude)) * (1 - inside)
+	}
+	return total
+}
@@ -0,0 +1,182 @@
+package systems
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// CoulombSI is e²/4πε₀, the strength of the Coulomb attraction between an
+// electron and a proton in SI units.
+const CoulombSI = 2.307077552e-28
+
+// Hydrogen is an electron bound to a point nucleus of charge Z, in a
+// superposition of orbitals. Each State holds (n, l, m) in N, with n ≥ 1,
+// 0 ≤ l < n and |m| ≤ l. The nucleus sits at the origin.
+//
+// Complex orbitals are the eigenstates of L_z, R_nl(r) Y_l^m(θ, φ). Real
+// orbitals combine m and -m into the familiar lobes: √2 Re Y_l^|m| for
+// m > 0, √2 Im Y_l^|m| for m < 0, so (2, 1, 1) is 2p_x and (2, 1, -1) is
+// 2p_y. Both forms are stationary since the energy depends on n alone.
+type Hydrogen struct {
+	Z       float64 // nuclear charge
+	States  []State // normalised superposition of (n, l, m)
+	Real    bool    // use real rather than complex spherical harmonics
+	Hbar    float64
+	Mass    float64
+	Coulomb float64 // e²/4πε₀ in the unit system, 1 in atomic units
+}
+
+// NewHydrogen returns an atom holding the given superposition of orbitals,
+// normalised. It fails if a state's quantum numbers are not allowed.
+func NewHydrogen(z float64, states []State, real bool, hbar, mass, coulomb float64) (*Hydrogen, error) {
+	if z <= 0 {
+		return nil, fmt.Errorf("nuclear charge must be positive, got %v", z)
+	}
+	for _, s := range states {
+		n, l, m := s.N[0], s.N[1], s.N[2]
+		if n < 1 || l < 0 || l >= n || m < -l || m > l {
+			return nil, fmt.Errorf("no orbital has n = %d, l = %d, m = %d: need n ≥ 1, 0 ≤ l < n and |m| ≤ l", n, l, m)
+		}
+	}
+	return &Hydrogen{Z: z, States: normalise(states), Real: real, Hbar: hbar, Mass: mass, Coulomb: coulomb}, nil
+}
+
+// Radius returns the Bohr radius scaled by the nuclear charge, a₀/Z.
+func (h *Hydrogen) Radius() float64 {
+	return h.Hbar * h.Hbar / (h.Mass * h.Coulomb * h.Z)
+}
+
+// Energy returns -Z²e⁴m / 2ħ²n², which depends only on the principal
+// quantum number n = N[0].
+func (h *Hydrogen) Energy(n [3]int) float64 {
+	k := h.Z * h.Coulomb / h.Hbar
+	return -h.Mass * k * k / (2 * float64(n[0]*n[0]))
+}
+
+// Radial returns R_nl(r), normalised so that ∫R² r² dr = 1.
+func (h *Hydrogen) Radial(n, l int, r float64) float64 {
+	rho := 2 * r / (float64(n) * h.Radius())
+	scale := 2 / (float64(n) * h.Radius())
+	// (n-l-1)! / 2n (n+l)!, through lgamma so large n do not overflow
+	lnRatio := lgamma(n-l) - lgamma(n+l+1) - math.Log(2*float64(n))
+	norm := math.Sqrt(scale * scale * scale * math.Exp(lnRatio))
+	return norm * math.Exp(-rho/2) * math.Pow(rho, float64(l)) * laguerre(n-l-1, float64(2*l+1), rho)
+}
+
+// lgamma returns ln((n-1)!).
+func lgamma(n int) float64 {
+	v, _ := math.Lgamma(float64(n))
+	return v
+}
+
+// laguerre returns the associated Laguerre polynomial L_k^α(x) by the
+// three term recurrence.
+func laguerre(k int, alpha, x float64) float64 {
+	prev, cur := 0.0, 1.0
+	for j := 0; j < k; j++ {
+		prev, cur = cur, ((2*float64(j)+1+alpha-x)*cur-(float64(j)+alpha)*prev)/float64(j+1)
+	}
+	return cur
+}
+
+// legendre returns the associated Legendre function P_l^m(x) for m ≥ 0,
+// including the Condon-Shortley phase (-1)^m.
+func legendre(l, m int, x float64) float64 {
+	pmm := 1.0
+	if m > 0 {
+		s := math.Sqrt((1 - x) * (1 + x))
+		fact := 1.0
+		for i := 1; i <= m; i++ {
+			pmm *= -fact * s
+			fact += 2
+		}
+	}
+	if l == m {
+		return pmm
+	}
+	pmm1 := x * float64(2*m+1) * pmm
+	for ll := m + 2; ll <= l; ll++ {
+		pmm, pmm1 = pmm1, (x*float64(2*ll-1)*pmm1-float64(ll+m-1)*pmm)/float64(ll-m)
+	}
+	return pmm1
+}
+
+// Harmonic returns the spherical harmonic Y_l^m(θ, φ), or its real form
+// when real is set.
+func Harmonic(l, m int, theta, phi float64, real bool) complex128 {
+	am := m
+	if am < 0 {
+		am = -am
+	}
+	norm := math.Sqrt(float64(2*l+1) / (4 * math.Pi) * math.Exp(lgamma(l-am+1)-lgamma(l+am+1)))
+	p := norm * legendre(l, am, math.Cos(theta))
+	if real {
+		switch {
+		case m > 0:
+			return complex(math.Sqrt2*sign(m)*p*math.Cos(float64(am)*phi), 0)
+		case m < 0:
+			return complex(math.Sqrt2*sign(m)*p*math.Sin(float64(am)*phi), 0)
+		}
+		return complex(p, 0)
+	}
+	y := complex(p, 0) * cmplx.Exp(complex(0, float64(am)*phi))
+	if m < 0 {
+		// Y_l^-m = (-1)^m conj(Y_l^m)
+		y = complex(sign(am), 0) * cmplx.Conj(y)
+	}
+	return y
+}
+
+// sign returns (-1)^m, which cancels the Condon-Shortley phase of the real
+// harmonics so every p orbital points along its positive axis.
+func sign(m int) float64 {
+	if m%2 == 0 {
+		return 1
+	}
+	return -1
+}
+
+// Orbital returns the stationary spatial part R_nl(r) Y_l^m(θ, φ) of the
+// state n = (n, l, m).
+func (h *Hydrogen) Orbital(n [3]int, x, y, z float64) complex128 {
+	r := math.Sqrt(x*x + y*y + z*z)
+	theta := 0.0
+	if r > 0 {
+		theta = math.Acos(z / r)
+	}
+	phi := math.Atan2(y, x)
+	return complex(h.Radial(n[0], n[1], r), 0) * Harmonic(n[1], n[2], theta, phi, h.Real)
+}
+
+// Evaluate returns ψ(x, y, z, t) for the superposition.
+func (h *Hydrogen) Evaluate(x, y, z, t float64) complex128 {
+	var waveFunction complex128
+	for _, s := range h.States {
+		phase := -h.Energy(s.N) * t / h.Hbar
+		waveFunction += s.Amplitude * h.Orbital(s.N, x, y, z) * cmplx.Exp(complex(0, phase))
+	}
+	return waveFunction
+}
+
+// Potential returns the Coulomb attraction -Ze²/4πε₀r.
+func (h *Hydrogen) Potential(x, y, z float64) float64 {
+	r := math.Sqrt(x*x + y*y + z*z)
+	return -h.Z * h.Coulomb / r
+}
+
+// Bounds returns a cube reaching out to 5n² scaled Bohr radii for the
+// largest n in the superposition, beyond which the density is negligible.
+func (h *Hydrogen) Bounds() (min, max [3]float64) {
+	n := 1
+	for _, s := range h.States {
+		if s.N[0] > n {
+			n = s.N[0]
+		}
+	}
+	reach := 5 * float64(n*n) * h.Radius()
+	for i := range min {
+		min[i], max[i] = -reach, reach
+	}
+	return min, max
+}
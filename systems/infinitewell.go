@@ -0,0 +1,99 @@
+package systems
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// State is one component of a superposition of eigenstates.
+type State struct {
+	N         [3]int     // quantum numbers for each dimension
+	Amplitude complex128 // coefficient before normalisation
+}
+
+// normalise returns a copy of states with the amplitudes rescaled so the
+// superposition of orthonormal eigenstates has unit norm. At least one
+// amplitude must be non-zero.
+func normalise(states []State) []State {
+	norm := 0.0
+	for _, s := range states {
+		norm += real(s.Amplitude)*real(s.Amplitude) + imag(s.Amplitude)*imag(s.Amplitude)
+	}
+	normalised := make([]State, len(states))
+	for i, s := range states {
+		normalised[i] = State{N: s.N, Amplitude: s.Amplitude / complex(math.Sqrt(norm), 0)}
+	}
+	return normalised
+}
+
+// InfiniteWell is a particle in a 1D, 2D or 3D box with infinitely high
+// walls. An axis with zero width is unused, so a well with Width {10, 10, 0}
+// is the 2D system drawn by the PurbatedSystem viewer.
+type InfiniteWell struct {
+	Width  [3]float64 // width of the well along each axis
+	States []State    // normalised superposition
+	V0     float64    // constant potential energy inside the well
+	Hbar   float64
+	Mass   float64
+}
+
+// NewInfiniteWell returns a well holding the given superposition, with the
+// amplitudes rescaled so the state is normalised.
+func NewInfiniteWell(width [3]float64, states []State, v0, hbar, mass float64) *InfiniteWell {
+	return &InfiniteWell{Width: width, States: normalise(states), V0: v0, Hbar: hbar, Mass: mass}
+}
+
+// Energy returns the energy of the eigenstate with quantum numbers n.
+func (w *InfiniteWell) Energy(n [3]int) float64 {
+	sum := 0.0
+	for i, a := range w.Width {
+		if a == 0 {
+			continue
+		}
+		k := float64(n[i]) * math.Pi / a
+		sum += k * k
+	}
+	return w.Hbar*w.Hbar*sum/(2*w.Mass) + w.V0
+}
+
+// Eigenstate returns the stationary spatial part of the eigenstate n.
+func (w *InfiniteWell) Eigenstate(n [3]int, x, y, z float64) float64 {
+	pos := [3]float64{x, y, z}
+	result := 1.0
+	for i, a := range w.Width {
+		if a == 0 {
+			continue
+		}
+		if pos[i] < 0 || pos[i] > a {
+			return 0
+		}
+		result *= math.Sqrt(2/a) * math.Sin(float64(n[i])*math.Pi*pos[i]/a)
+	}
+	return result
+}
+
+// Evaluate returns ψ(x, y, z, t) for the superposition.
+func (w *InfiniteWell) Evaluate(x, y, z, t float64) complex128 {
+	var waveFunction complex128
+	for _, s := range w.States {
+		phase := -w.Energy(s.N) * t / w.Hbar
+		waveFunction += s.Amplitude * complex(w.Eigenstate(s.N, x, y, z), 0) * cmplx.Exp(complex(0, phase))
+	}
+	return waveFunction
+}
+
+// Potential returns V0 inside the well and +Inf outside it.
+func (w *InfiniteWell) Potential(x, y, z float64) float64 {
+	pos := [3]float64{x, y, z}
+	for i, a := range w.Width {
+		if a != 0 && (pos[i] < 0 || pos[i] > a) {
+			return math.Inf(1)
+		}
+	}
+	return w.V0
+}
+
+// Bounds returns the box [0, Width] along each axis.
+func (w *InfiniteWell) Bounds() (min, max [3]float64) {
+	return [3]float64{}, w.Width
+}
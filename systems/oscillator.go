@@ -0,0 +1,205 @@
+package systems
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// harmonic is the potential ½mω²x² along each axis, centred on the origin.
+// An axis with zero frequency is unused.
+type harmonic struct {
+	Omega [3]float64 // angular frequency along each axis
+	Hbar  float64
+	Mass  float64
+}
+
+// length returns the oscillator length √(ħ/mω) of an axis, the spread of
+// its ground state.
+func (h harmonic) length(axis int) float64 {
+	return math.Sqrt(h.Hbar / (h.Mass * h.Omega[axis]))
+}
+
+// Potential returns ½mω²x² summed over the axes in use.
+func (h harmonic) Potential(x, y, z float64) float64 {
+	pos := [3]float64{x, y, z}
+	v := 0.0
+	for i, w := range h.Omega {
+		v += h.Mass * w * w * pos[i] * pos[i] / 2
+	}
+	return v
+}
+
+// Oscillator is a superposition of eigenstates of an anisotropic harmonic
+// oscillator in 1D, 2D or 3D. Quantum numbers count from 0, the ground
+// state.
+type Oscillator struct {
+	harmonic
+	States []State // normalised superposition
+}
+
+// NewOscillator returns an oscillator holding the given superposition,
+// normalised.
+func NewOscillator(omega [3]float64, states []State, hbar, mass float64) *Oscillator {
+	return &Oscillator{
+		harmonic: harmonic{Omega: omega, Hbar: hbar, Mass: mass},
+		States:   normalise(states),
+	}
+}
+
+// Energy returns ħω(n + ½) summed over the axes in use.
+func (o *Oscillator) Energy(n [3]int) float64 {
+	e := 0.0
+	for i, w := range o.Omega {
+		if w != 0 {
+			e += o.Hbar * w * (float64(n[i]) + 0.5)
+		}
+	}
+	return e
+}
+
+// Eigenstate returns the stationary spatial part of the eigenstate n, a
+// product of Hermite functions.
+func (o *Oscillator) Eigenstate(n [3]int, x, y, z float64) float64 {
+	pos := [3]float64{x, y, z}
+	result := 1.0
+	for i, w := range o.Omega {
+		if w == 0 {
+			continue
+		}
+		l := o.length(i)
+		result *= hermiteFunction(n[i], pos[i]/l) / math.Sqrt(l)
+	}
+	return result
+}
+
+// hermiteFunction returns the normalised Hermite function
+// Hₙ(ξ) exp(-ξ²/2) / √(2ⁿ n! √π). It runs the Hermite polynomial recurrence
+// on the normalised functions themselves, which stays finite where Hₙ and
+// n! would overflow.
+func hermiteFunction(n int, xi float64) float64 {
+	prev, cur := 0.0, math.Pow(math.Pi, -0.25)*math.Exp(-xi*xi/2)
+	for k := 1; k <= n; k++ {
+		prev, cur = cur, math.Sqrt(2/float64(k))*xi*cur-math.Sqrt(float64(k-1)/float64(k))*prev
+	}
+	return cur
+}
+
+// Evaluate returns ψ(x, y, z, t) for the superposition.
+func (o *Oscillator) Evaluate(x, y, z, t float64) complex128 {
+	var waveFunction complex128
+	for _, s := range o.States {
+		phase := -o.Energy(s.N) * t / o.Hbar
+		waveFunction += s.Amplitude * complex(o.Eigenstate(s.N, x, y, z), 0) * cmplx.Exp(complex(0, phase))
+	}
+	return waveFunction
+}
+
+// Bounds returns a box reaching four oscillator lengths past the classical
+// turning points of the most energetic state along each axis.
+func (o *Oscillator) Bounds() (min, max [3]float64) {
+	for i, w := range o.Omega {
+		if w == 0 {
+			continue
+		}
+		n := 0
+		for _, s := range o.States {
+			if s.N[i] > n {
+				n = s.N[i]
+			}
+		}
+		reach := o.length(i) * (math.Sqrt(2*float64(n)+1) + 4)
+		min[i], max[i] = -reach, reach
+	}
+	return min, max
+}
+
+// Gaussian is a coherent or squeezed state of a harmonic oscillator. Along
+// each axis it is the ground state squeezed by e^-r and displaced by the
+// coherent amplitude α, so x₀ = √(2ħ/mω) Re α and p₀ = √(2ħmω) Im α. A
+// Gaussian stays Gaussian in a harmonic potential, so it is evolved exactly:
+// the centre follows the classical orbit and the width breathes at twice
+// the oscillator frequency unless r is zero.
+type Gaussian struct {
+	harmonic
+	Alpha   [3]complex128 // coherent amplitude along each axis
+	Squeeze [3]float64    // squeeze parameter r, positive narrows the state in position
+}
+
+// NewGaussian returns the coherent state α squeezed by r along each axis.
+func NewGaussian(omega [3]float64, alpha [3]complex128, squeeze [3]float64, hbar, mass float64) *Gaussian {
+	return &Gaussian{
+		harmonic: harmonic{Omega: omega, Hbar: hbar, Mass: mass},
+		Alpha:    alpha,
+		Squeeze:  squeeze,
+	}
+}
+
+// Centre returns the position and momentum of the centre of the state at
+// time t, which follow the classical orbit.
+func (g *Gaussian) Centre(t float64) (q, p [3]float64) {
+	for i, w := range g.Omega {
+		if w == 0 {
+			continue
+		}
+		q0, p0 := g.start(i)
+		c, s := math.Cos(w*t), math.Sin(w*t)
+		q[i] = q0*c + p0/(g.Mass*w)*s
+		p[i] = p0*c - g.Mass*w*q0*s
+	}
+	return q, p
+}
+
+// start returns the centre of an axis at t = 0.
+func (g *Gaussian) start(axis int) (q0, p0 float64) {
+	w := g.Omega[axis]
+	a := g.Alpha[axis]
+	return math.Sqrt(2*g.Hbar/(g.Mass*w)) * real(a), math.Sqrt(2*g.Hbar*g.Mass*w) * imag(a)
+}
+
+// Evaluate returns ψ(x, y, z, t), a product over the axes of
+//
+//	(mω Im b₀ / πħ)^¼ D^-½ exp(i[mωb(x-q)²/2 + p(x-q) + (pq - p₀q₀)/2]/ħ)
+//
+// where b₀ = i e^2r, D = cos ωt + b₀ sin ωt and b = (b₀ cos ωt - sin ωt)/D.
+// The square root of D follows D continuously round the origin, once per
+// period, which gives the coherent state its zero-point phase e^-iωt/2.
+func (g *Gaussian) Evaluate(x, y, z, t float64) complex128 {
+	pos := [3]float64{x, y, z}
+	q, p := g.Centre(t)
+	psi := complex(1, 0)
+	for i, w := range g.Omega {
+		if w == 0 {
+			continue
+		}
+		q0, p0 := g.start(i)
+		b0 := complex(0, math.Exp(2*g.Squeeze[i]))
+		c, s := complex(math.Cos(w*t), 0), complex(math.Sin(w*t), 0)
+		d := c + b0*s
+		b := (b0*c - s) / d
+
+		// Unwrap the phase of D, which passes -1 at ωt = π, 3π, ...
+		arg := cmplx.Phase(d) + 2*math.Pi*math.Round(w*t/(2*math.Pi))
+		root := complex(math.Sqrt(cmplx.Abs(d)), 0) * cmplx.Exp(complex(0, arg/2))
+
+		u := pos[i] - q[i]
+		exponent := complex(g.Mass*w*u*u/2, 0)*b + complex(p[i]*u+(p[i]*q[i]-p0*q0)/2, 0)
+		norm := math.Pow(g.Mass*w*imag(b0)/(math.Pi*g.Hbar), 0.25)
+		psi *= complex(norm, 0) / root * cmplx.Exp(complex(0, 1)*exponent/complex(g.Hbar, 0))
+	}
+	return psi
+}
+
+// Bounds returns a box holding the whole orbit of the centre, padded by
+// four times the widest the state becomes along each axis.
+func (g *Gaussian) Bounds() (min, max [3]float64) {
+	for i, w := range g.Omega {
+		if w == 0 {
+			continue
+		}
+		q0, p0 := g.start(i)
+		amplitude := math.Hypot(q0, p0/(g.Mass*w))
+		widest := g.length(i) * math.Exp(math.Abs(g.Squeeze[i])) / math.Sqrt2
+		min[i], max[i] = -amplitude-4*widest, amplitude+4*widest
+	}
+	return min, max
+}
@@ -0,0 +1,67 @@
+// Package systems holds the quantum systems the viewers can display. Every
+// system is evaluated through the same ψ(x, y, z, t) contract as the
+// calculateWaveFunction functions in the viewers, so any of them can be
+// dropped into a render loop or an exporter.
+package systems
+
+import "math/cmplx"
+
+// Physical constants used when a scenario asks for SI units.
+const (
+	HbarSI       = 1.0545718e-34  // reduced Planck's constant
+	ElectronMass = 9.10938356e-31 // mass of the particle
+)
+
+// Evaluator is anything that can be evaluated at (x, y, z, t).
+type Evaluator interface {
+	// Evaluate returns ψ(x, y, z, t).
+	Evaluate(x, y, z, t float64) complex128
+}
+
+// System is a wavefunction together with the potential and region of space
+// it lives in.
+type System interface {
+	Evaluator
+	// Potential returns V(x, y, z).
+	Potential(x, y, z float64) float64
+	// Bounds returns the corners of the box the system occupies.
+	Bounds() (min, max [3]float64)
+}
+
+// WaveFunction adapts a bare calculateWaveFunction-style function to the
+// evaluate-at-(x,y,z,t) contract.
+type WaveFunction func(x, y, z, t float64) complex128
+
+// Evaluate calls f.
+func (f WaveFunction) Evaluate(x, y, z, t float64) complex128 {
+	return f(x, y, z, t)
+}
+
+// Quantities lists the ways a wavefunction value can be reduced to a number
+// for display.
+var Quantities = []string{"probability", "real", "imag", "phase"}
+
+// IsQuantity reports whether name is one of Quantities.
+func IsQuantity(name string) bool {
+	for _, q := range Quantities {
+		if q == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Quantity reduces ψ to the named quantity: |ψ|², its real or imaginary
+// part, or its phase.
+func Quantity(psi complex128, name string) float64 {
+	switch name {
+	case "real":
+		return real(psi)
+	case "imag":
+		return imag(psi)
+	case "phase":
+		return cmplx.Phase(psi)
+	}
+	abs := cmplx.Abs(psi)
+	return abs * abs
+}